@@ -7,9 +7,11 @@ import (
 )
 
 // Node AST 中的每个节 点都必须实现 Node 接口，也就是说必须提供 TokenLiteral()方法，该方法返回与其 关联的词法单元的字面量
+// Pos 返回节点在源码中的起始位置
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -22,6 +24,13 @@ type Expression interface {
 	expressionNode() // 仅占位，防止 Statement 和 Expression 混淆
 }
 
+// Pattern 用于绑定变量的模式，可以出现在 let 语句和函数形参中
+// 模式可以是标识符，也可以是解构数组和哈希表的 ArrayPattern、HashPattern
+type Pattern interface {
+	Expression
+	patternNode()
+}
+
 // Program 节点是语法分析器生成的每个 AST 的根节点
 type Program struct {
 	Statements []Statement
@@ -35,6 +44,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
@@ -45,17 +61,23 @@ func (p *Program) String() string {
 
 // LetStatement let 语句节点
 type LetStatement struct {
-	Token token.Token // token.LET 词法单元
-	Name  *Identifier // 左侧标识符
-	Value Expression  // 右侧表达式、字面量
+	Token   token.Token // token.LET 词法单元
+	Name    *Identifier // 左侧标识符 解构赋值时为 nil
+	Pattern Pattern     // 解构赋值时左侧的模式 let [a, b] = xs; 普通的 let 语句为 nil
+	Value   Expression  // 右侧表达式、字面量
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -67,12 +89,13 @@ func (ls *LetStatement) String() string {
 type FunctionDeclarationStatement struct {
 	Token      token.Token
 	Name       *Identifier
-	Parameters []*Identifier
+	Parameters []Pattern
 	Body       *BlockStatement
 }
 
 func (fs *FunctionDeclarationStatement) statementNode()       {}
 func (fs *FunctionDeclarationStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionDeclarationStatement) Pos() token.Position  { return fs.Token.Pos }
 func (fs *FunctionDeclarationStatement) String() string {
 	var out bytes.Buffer
 	var params []string
@@ -90,20 +113,37 @@ func (fs *FunctionDeclarationStatement) String() string {
 }
 
 // AssignExpression 赋值表达式节点
+// 包括 = 赋值、+= 等复合赋值 以及 ++ 和 -- 前缀、后缀自增自减
 type AssignExpression struct {
-	Token token.Token // token.ASSIGN 词法单元
-	Left  Expression  // 左侧标识符、索引表达式
-	Value Expression  // 右侧表达式、字面量
+	Token    token.Token // token.ASSIGN 等赋值运算符词法单元
+	Left     Expression  // 左侧标识符、索引表达式、成员表达式
+	Operator string      // =, +=, -=, *=, /=, %=, ++, --
+	Value    Expression  // 右侧表达式、字面量 自增自减时为 nil
+	Prefix   bool        // 是否为前缀自增自减 ++x --x
 }
 
 func (ae *AssignExpression) expressionNode()      {}
 func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Left != nil && !ae.Prefix {
+		return ae.Left.Pos()
+	}
+	return ae.Token.Pos
+}
 func (ae *AssignExpression) String() string {
 	var out bytes.Buffer
 	out.WriteByte('(')
+	if ae.Prefix {
+		out.WriteString(ae.Operator)
+		out.WriteString(ae.Left.String())
+		out.WriteByte(')')
+		return out.String()
+	}
 	out.WriteString(ae.Left.String())
-	out.WriteString("=")
-	out.WriteString(ae.Value.String())
+	out.WriteString(ae.Operator)
+	if ae.Value != nil {
+		out.WriteString(ae.Value.String())
+	}
 	out.WriteByte(')')
 	return out.String()
 }
@@ -115,11 +155,127 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) patternNode()         {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string {
 	return i.Value
 }
 
+// ArrayPattern 解构数组的模式 [a, [b, c], ...rest]
+type ArrayPattern struct {
+	Token    token.Token // [ 词法单元
+	Elements []Pattern
+	Rest     *Identifier // ...rest 绑定剩余的元素 可以为 nil
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) String() string {
+	var elements []string
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPatternPair 哈希表模式中的一项 {key: value}
+type HashPatternPair struct {
+	Key   *Identifier // 键名 对应哈希表中的字符串键
+	Value Pattern     // 绑定对应值的模式 {name} 的简写形式中为与键同名的标识符
+}
+
+// HashPattern 解构哈希表的模式 {name, age: years}
+type HashPattern struct {
+	Token token.Token // { 词法单元
+	Pairs []HashPatternPair
+}
+
+func (hp *HashPattern) expressionNode()      {}
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) String() string {
+	var pairs []string
+	for _, pair := range hp.Pairs {
+		if ident, ok := pair.Value.(*Identifier); ok && ident.Value == pair.Key.Value {
+			pairs = append(pairs, pair.Key.String())
+			continue
+		}
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// DefaultPattern 带默认值的函数形参 b = 2
+// 调用时没有传入对应的实参则在函数的作用域中对 Default 求值
+type DefaultPattern struct {
+	Token   token.Token // = 词法单元
+	Pattern Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) expressionNode()      {}
+func (dp *DefaultPattern) patternNode()         {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) Pos() token.Position  { return dp.Pattern.Pos() }
+func (dp *DefaultPattern) String() string {
+	return dp.Pattern.String() + " = " + dp.Default.String()
+}
+
+// RestPattern 可变参数 ...rest 只能作为最后一个函数形参 绑定剩余的实参组成的数组
+type RestPattern struct {
+	Token token.Token // ... 词法单元
+	Name  *Identifier
+}
+
+func (rp *RestPattern) expressionNode()      {}
+func (rp *RestPattern) patternNode()         {}
+func (rp *RestPattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RestPattern) Pos() token.Position  { return rp.Token.Pos }
+func (rp *RestPattern) String() string {
+	return "..." + rp.Name.String()
+}
+
+// LiteralPattern 字面量模式 只出现在 match 分支中 值相等时匹配成功
+// 1, -1, 2.5, "a", true
+type LiteralPattern struct {
+	Token token.Token // 字面量的第一个词法单元
+	Value Expression
+}
+
+func (lp *LiteralPattern) expressionNode()      {}
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Token.Pos }
+func (lp *LiteralPattern) String() string {
+	return lp.Value.String()
+}
+
+// AlternativePattern 用 | 连接的多个模式 只出现在 match 分支中 任意一个模式匹配即成功
+// 1 | 2 | 3
+type AlternativePattern struct {
+	Token        token.Token // 第一个 | 词法单元
+	Alternatives []Pattern
+}
+
+func (ap *AlternativePattern) expressionNode()      {}
+func (ap *AlternativePattern) patternNode()         {}
+func (ap *AlternativePattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *AlternativePattern) Pos() token.Position  { return ap.Alternatives[0].Pos() }
+func (ap *AlternativePattern) String() string {
+	var alts []string
+	for _, alt := range ap.Alternatives {
+		alts = append(alts, alt.String())
+	}
+	return strings.Join(alts, " | ")
+}
+
 // ReturnStatement 返回语句
 type ReturnStatement struct {
 	Token       token.Token
@@ -128,6 +284,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
@@ -138,6 +295,119 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement 循环语句
+// while (<condition>) <block statement>
+type WhileStatement struct {
+	Token     token.Token // token.WHILE 词法单元
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
+
+// ThrowStatement 抛出错误的语句
+// throw <expression>;
+type ThrowStatement struct {
+	Token token.Token // token.THROW 词法单元
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// TryStatement 捕获错误的语句 catch 和 finally 至少出现一个
+// try <block statement> catch (<identifier>) <block statement> finally <block statement>
+type TryStatement struct {
+	Token   token.Token // token.TRY 词法单元
+	Block   *BlockStatement
+	Param   *Identifier     // catch 绑定错误的标识符 可以省略 catch { }
+	Catch   *BlockStatement // 没有 catch 时为 nil
+	Finally *BlockStatement // 没有 finally 时为 nil
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.Param != nil {
+			out.WriteString("(" + ts.Param.String() + ") ")
+		}
+		out.WriteString(ts.Catch.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+	return out.String()
+}
+
+// ForStatement 遍历数组、哈希表、字符串和区间的循环语句
+// for (<identifier> in <expression>) <block statement>
+// for (<identifier>, <identifier> in <expression>) <block statement>
+type ForStatement struct {
+	Token     token.Token   // token.FOR 词法单元
+	Variables []*Identifier // 循环变量 一个或者两个
+	Iterable  Expression
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	var vars []string
+	for _, v := range fs.Variables {
+		vars = append(vars, v.String())
+	}
+	out.WriteString("for(")
+	out.WriteString(strings.Join(vars, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+// BreakStatement 跳出循环语句
+type BreakStatement struct {
+	Token token.Token // token.BREAK 词法单元
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+// ContinueStatement 跳过本次循环剩余部分的语句
+type ContinueStatement struct {
+	Token token.Token // token.CONTINUE 词法单元
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
 // BlockStatement 由 {} 包裹的多条语句组成的语句块
 type BlockStatement struct {
 	Token      token.Token // { 词法单元
@@ -146,6 +416,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	var stmts []string
@@ -164,6 +435,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -179,6 +451,7 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
 // FloatLiteral 浮点数字面量表达式
@@ -189,6 +462,7 @@ type FloatLiteral struct {
 
 func (fl *FloatLiteral) expressionNode()      {}
 func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
 
 // BooleanLiteral 布尔字面量表达式节点
@@ -199,6 +473,7 @@ type BooleanLiteral struct {
 
 func (bl *BooleanLiteral) expressionNode()      {}
 func (bl *BooleanLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BooleanLiteral) Pos() token.Position  { return bl.Token.Pos }
 func (bl *BooleanLiteral) String() string       { return bl.Token.Literal }
 
 // StringLiteral 字符串字面量节点
@@ -209,8 +484,34 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// InterpolatedString 插值字符串节点 "hello ${name}"
+type InterpolatedString struct {
+	Token token.Token  // TEMPLATE_HEAD 词法单元
+	Parts []Expression // 按顺序排列的字符串片段(*StringLiteral)和插值表达式
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteByte('"')
+	for _, part := range is.Parts {
+		if sl, ok := part.(*StringLiteral); ok {
+			out.WriteString(sl.Value)
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
 // PrefixExpression  前缀表达式
 type PrefixExpression struct {
 	Token    token.Token // 前缀词法单元，如!
@@ -220,6 +521,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -239,6 +541,12 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -259,6 +567,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -272,15 +581,54 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// MatchExpression match 表达式
+// match (<expression>) { <pattern> [if <guard>] => <body>, ... }
+type MatchExpression struct {
+	Token   token.Token // match 词法单元
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm match 表达式的一个分支
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression      // if 之后的守卫条件 可以为 nil
+	Body    *BlockStatement // => 之后为单个表达式时包装为只有一条语句的语句块
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) String() string {
+	var arms []string
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match (" + me.Subject.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
 // FunctionLiteral 函数字面量表达式节点
 type FunctionLiteral struct {
 	Token      token.Token     // fn 词法单元
-	Parameters []*Identifier   // 形参列表
+	Parameters []Pattern       // 形参列表
 	Body       *BlockStatement // 语句块
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	var params []string
@@ -301,10 +649,17 @@ type CallExpression struct {
 	Token     token.Token  // ( 词法单元
 	Function  Expression   // 标识符或者字面量
 	Arguments []Expression // 实参
+	Tail      bool         // 是否处于函数体的尾部位置 由语法分析器标记
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(ce.Function.String())
@@ -318,6 +673,21 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// KeywordArgument 关键字参数 connect(host: "db") 按名字匹配函数的形参
+// 只能出现在函数实参列表中 并且必须位于所有位置参数之后
+type KeywordArgument struct {
+	Token token.Token // 参数名的 token.IDENT 词法单元
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) Pos() token.Position  { return ka.Token.Pos }
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 // ArrayLiteral 数组字面量节点
 type ArrayLiteral struct {
 	Token    token.Token
@@ -326,6 +696,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var buf bytes.Buffer
 	var elms []string
@@ -338,6 +709,20 @@ func (al *ArrayLiteral) String() string {
 	return buf.String()
 }
 
+// SpreadExpression 展开表达式 ...xs 只能出现在函数实参和数组字面量中
+// 将数组的元素逐个展开到所在的列表
+type SpreadExpression struct {
+	Token token.Token // ... 词法单元
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
 // IndexExpression 数组索引表达值节点
 type IndexExpression struct {
 	Token token.Token
@@ -347,6 +732,12 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
 func (ie *IndexExpression) String() string {
 	var buf bytes.Buffer
 	buf.WriteByte('(')
@@ -358,18 +749,83 @@ func (ie *IndexExpression) String() string {
 	return buf.String()
 }
 
+// SliceExpression 切片表达式节点 a[start:end:step]，三个部分都可以省略
+type SliceExpression struct {
+	Token token.Token // [ 词法单元
+	Left  Expression
+	Start Expression // 可以为 nil
+	End   Expression // 可以为 nil
+	Step  Expression // 可以为 nil
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position {
+	if se.Left != nil {
+		return se.Left.Pos()
+	}
+	return se.Token.Pos
+}
+func (se *SliceExpression) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	buf.WriteString(se.Left.String())
+	buf.WriteByte('[')
+	if se.Start != nil {
+		buf.WriteString(se.Start.String())
+	}
+	buf.WriteByte(':')
+	if se.End != nil {
+		buf.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		buf.WriteByte(':')
+		buf.WriteString(se.Step.String())
+	}
+	buf.WriteByte(']')
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// MemberExpression 成员访问表达式节点 user.name
+type MemberExpression struct {
+	Token  token.Token // token.DOT 词法单元
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() token.Position {
+	if me.Object != nil {
+		return me.Object.Pos()
+	}
+	return me.Token.Pos
+}
+func (me *MemberExpression) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	buf.WriteString(me.Object.String())
+	buf.WriteByte('.')
+	buf.WriteString(me.Member.String())
+	buf.WriteByte(')')
+	return buf.String()
+}
+
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression // 按照源码中出现的顺序记录的键
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
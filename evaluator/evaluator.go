@@ -1,9 +1,12 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 	"monkey/ast"
 	"monkey/object"
+	"strings"
 )
 
 // 以下对象全局都是一致的，无需在每次使用时都重复创建
@@ -11,9 +14,22 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// Eval 对 AST 节点求值
+// 求值出错时 将最先感知到错误的节点的位置记录为错误的位置
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	val := eval(node, env)
+	if err, ok := val.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return val
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch v := node.(type) {
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: v.Value}
@@ -23,6 +39,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return nativeBoolToBooleanObject(v.Value)
 	case *ast.StringLiteral:
 		return &object.String{Value: v.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(v.Parts, env)
 	case *ast.Identifier:
 		return evalIdentifier(v, env)
 	case *ast.FunctionLiteral:
@@ -34,7 +52,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ArrayLiteral:
 		return evalArrayLiteral(v.Elements, env)
 	case *ast.HashLiteral:
-		return evalHashLiteral(v.Pairs, env)
+		return evalHashLiteral(v, env)
 	case *ast.IndexExpression:
 		left := Eval(v.Left, env)
 		if isError(left) {
@@ -45,6 +63,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index, env)
+	case *ast.SliceExpression:
+		left := Eval(v.Left, env)
+		if isError(left) {
+			return left
+		}
+		bounds := make([]object.Object, 3)
+		for i, exp := range []ast.Expression{v.Start, v.End, v.Step} {
+			if exp == nil {
+				continue
+			}
+			bounds[i] = Eval(exp, env)
+			if isError(bounds[i]) {
+				return bounds[i]
+			}
+		}
+		return evalSliceExpression(left, bounds[0], bounds[1], bounds[2])
+	case *ast.MemberExpression:
+		obj := Eval(v.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, v.Member.Value)
 	case *ast.Program:
 		return evalProgram(v.Statements, env)
 	case *ast.ExpressionStatement:
@@ -59,7 +99,33 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.WhileStatement:
+		return evalWhileStatement(v, env)
+	case *ast.ThrowStatement:
+		val := Eval(v.Value, env)
+		if isError(val) {
+			return val
+		}
+		return throwError(val)
+	case *ast.TryStatement:
+		return evalTryStatement(v, env)
+	case *ast.ForStatement:
+		return evalForStatement(v, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.LetStatement:
+		if v.Pattern != nil {
+			val := Eval(v.Value, env)
+			if isError(val) {
+				return val
+			}
+			if err := bindPattern(v.Pattern, val, env); err != nil {
+				return err
+			}
+			return NULL
+		}
 		_, ok := env.GetLocal(v.Name.Value)
 		if ok {
 			return newError("identifier exist: " + v.Name.Value)
@@ -75,17 +141,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return newError("identifier exist: " + v.Name.Value)
 		}
 		f := &object.Function{
+			Name:       v.Name.Value,
 			Parameters: v.Parameters,
 			Body:       v.Body,
 			Env:        env,
 		}
 		env.Set(v.Name.Value, f)
 	case *ast.AssignExpression:
-		val := Eval(v.Value, env)
-		if isError(val) {
-			return val
-		}
-		return evalAssignExpression(v.Left, val, env)
+		return evalAssignExpression(v, env)
 	case *ast.PrefixExpression:
 		val := Eval(v.Right, env)
 		if isError(val) {
@@ -97,6 +160,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(lVal) {
 			return lVal
 		}
+		if v.Operator == "&&" || v.Operator == "||" {
+			return evalLogicalExpression(v.Operator, lVal, v.Right, env)
+		}
 		rVal := Eval(v.Right, env)
 		if isError(rVal) {
 			return rVal
@@ -108,16 +174,30 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 		return evalIfExpression(val, v.Consequence, v.Alternative, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(v, env)
 	case *ast.CallExpression:
 		val := Eval(v.Function, env) // val is function object
 		if isError(val) {
 			return val
 		}
-		args := evalExpressions(v.Arguments, env) // 首先对实参表达式求值
-		if len(args) != 0 && isError(args[0]) {
-			return args[0]
+		args, err := evalArguments(val, v.Arguments, env) // 首先对实参表达式求值
+		if err != nil {
+			return err
+		}
+		if f, ok := val.(*object.Function); ok && v.Tail {
+			// 尾调用交给正在执行的 applyFunction 完成
+			return &object.TailCall{Function: f, Arguments: args, Pos: v.Pos()}
 		}
-		return applyFunction(val, args)
+		res := applyFunction(val, args)
+		if err, ok := res.(*object.Error); ok {
+			// applyFunction 记录的调用帧不知道调用的位置 在这里补充
+			// 内置函数中调用的回调函数 例如 map 的参数 调用位置记为内置函数的调用位置
+			for i := len(err.Stack) - 1; i >= 0 && !err.Stack[i].Pos.IsValid(); i-- {
+				err.Stack[i].Pos = v.Pos()
+			}
+		}
+		return res
 	}
 	return NULL
 }
@@ -139,6 +219,10 @@ func evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.O
 			// 递归结束后 在最上层的 block 即可正确感知到应该在第一个 ReturnValue 处返回
 			return result
 		}
+		// break 和 continue 同理 需要一直传递到最近的循环
+		if result == BREAK || result == CONTINUE {
+			return result
+		}
 	}
 	return result
 }
@@ -159,11 +243,259 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 		case *object.Error:
 			// 求值的到解析错误则立刻返回
 			return result
+		case *object.Break, *object.Continue:
+			return loopControlError(result)
+		}
+	}
+	return result
+}
+
+// throwError 将 throw 的值转换为错误
+// 字符串作为错误信息 哈希表可以通过 message 和 kind 键指定错误信息和类别
+// 因此 catch 得到的错误可以被再次 throw
+func throwError(val object.Object) *object.Error {
+	err := &object.Error{Message: val.Inspect(), Kind: object.THROWN_ERROR}
+	switch val := val.(type) {
+	case *object.String:
+		err.Message = val.Value
+	case *object.Hash:
+		if msg, ok := hashGet(val, "message"); ok {
+			err.Message = msg.Inspect()
+			if str, ok := msg.(*object.String); ok {
+				err.Message = str.Value
+			}
+		}
+		if kind, ok := hashGet(val, "kind"); ok {
+			if str, ok := kind.(*object.String); ok {
+				err.Kind = str.Value
+			}
+		}
+	}
+	return err
+}
+
+// evalTryStatement 对 try 语句求值
+// try 语句块产生错误时对 catch 语句块求值 错误以哈希表的形式绑定到 catch 的标识符上
+// finally 语句块总是会被求值 其中的 return、break、continue 和错误会覆盖之前的结果
+// 否则 try 或 catch 中的 return、break、continue 和错误在 finally 之后继续向上传递
+func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(ts.Block, env)
+	if err, ok := result.(*object.Error); ok && ts.Catch != nil {
+		catchEnv := object.NewEnclosedEnviroment(env)
+		if ts.Param != nil {
+			catchEnv.Set(ts.Param.Value, errorToHash(err))
 		}
+		result = Eval(ts.Catch, catchEnv)
+	}
+	if ts.Finally != nil {
+		fin := Eval(ts.Finally, env)
+		switch fin.(type) {
+		case *object.Error, *object.ReturnValue, *object.Break, *object.Continue:
+			return fin
+		}
+	}
+	if result == nil {
+		// 空的语句块求值结果为 nil
+		return NULL
 	}
 	return result
 }
 
+// errorToHash 将错误转换为 catch 中可以访问的哈希表
+// {"message": ..., "kind": ..., "position": "line:column"} 位置未知时 position 为 null
+func errorToHash(err *object.Error) *object.Hash {
+	var pos object.Object = NULL
+	if err.Pos.IsValid() {
+		pos = &object.String{Value: err.Pos.String()}
+	}
+	hash := object.NewHash()
+	for _, field := range []struct {
+		key   string
+		value object.Object
+	}{
+		{"message", &object.String{Value: err.Message}},
+		{"kind", &object.String{Value: err.Kind}},
+		{"position", pos},
+	} {
+		key := &object.String{Value: field.key}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: field.value})
+	}
+	return hash
+}
+
+// hashGet 读取哈希表中字符串键对应的值
+func hashGet(hash *object.Hash, key string) (object.Object, bool) {
+	k := &object.String{Value: key}
+	pair, ok := hash.Pairs[k.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+// evalWhileStatement 对 while 循环求值
+// 每次循环都为循环体创建新的块级作用域 循环语句本身的值为 NULL
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		cond := Eval(ws.Condition, env)
+		if isError(cond) {
+			return cond
+		}
+		if !isTruthy(cond) {
+			return NULL
+		}
+		if result := evalLoopBody(ws.Body, env); result != nil {
+			return result
+		}
+	}
+}
+
+// evalForStatement 对 for ... in 循环求值
+// 只有一个循环变量时 数组、字符串和区间绑定元素 哈希表绑定键
+// 有两个循环变量时 数组、字符串和区间绑定下标和元素 哈希表绑定键和值
+// 每次循环都在新的作用域中绑定循环变量 因此闭包捕获到的是本次循环的值
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	iterate := func(first, second object.Object) object.Object {
+		loopEnv := object.NewEnclosedEnviroment(env)
+		if len(fs.Variables) == 2 {
+			loopEnv.Set(fs.Variables[0].Value, first)
+			loopEnv.Set(fs.Variables[1].Value, second)
+		} else {
+			loopEnv.Set(fs.Variables[0].Value, second)
+		}
+		return evalLoopBody(fs.Body, loopEnv)
+	}
+
+	switch v := iterable.(type) {
+	case object.Array:
+		for i, elem := range v {
+			if result := iterate(object.NewInteger(int64(i)), elem); result != nil {
+				return result
+			}
+		}
+	case *object.String:
+		i := 0
+		for _, ch := range v.Value {
+			if result := iterate(object.NewInteger(int64(i)), &object.String{Value: string(ch)}); result != nil {
+				return result
+			}
+			i++
+		}
+	case *object.Range:
+		for i := v.Start; i < v.End; i++ {
+			if result := iterate(object.NewInteger(i-v.Start), object.NewInteger(i)); result != nil {
+				return result
+			}
+		}
+	case *object.Hash:
+		// 循环体中新增的键不会被遍历到
+		for _, key := range v.Keys {
+			pair := v.Pairs[key]
+			value := pair.Value
+			if len(fs.Variables) == 1 {
+				value = pair.Key
+			}
+			if result := iterate(pair.Key, value); result != nil {
+				return result
+			}
+		}
+	default:
+		return newError("%s is not iterable", iterable.Type())
+	}
+	return NULL
+}
+
+// bindPattern 按照模式解构 val 并将得到的值绑定到 env 中
+// 同一个作用域中已经存在的标识符不能被重复绑定
+func bindPattern(pattern ast.Pattern, val object.Object, env *object.Environment) *object.Error {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if _, ok := env.GetLocal(p.Value); ok {
+			return newError("identifier exist: " + p.Value)
+		}
+		env.Set(p.Value, val)
+	case *ast.ArrayPattern:
+		arr, ok := val.(object.Array)
+		if !ok {
+			return newError("cannot destructure %s as array: %s", val.Type(), p.String())
+		}
+		if p.Rest == nil && len(arr) != len(p.Elements) {
+			return newError("array pattern %s expects %d elements, got %d", p.String(), len(p.Elements), len(arr))
+		}
+		if p.Rest != nil && len(arr) < len(p.Elements) {
+			return newError("array pattern %s expects at least %d elements, got %d", p.String(), len(p.Elements), len(arr))
+		}
+		for i, elem := range p.Elements {
+			if err := bindPattern(elem, arr[i], env); err != nil {
+				return err
+			}
+		}
+		if p.Rest != nil {
+			// 剩余的元素总是复制到新的数组中
+			rest := make(object.Array, len(arr)-len(p.Elements))
+			copy(rest, arr[len(p.Elements):])
+			return bindPattern(p.Rest, rest, env)
+		}
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newError("cannot destructure %s as hash: %s", val.Type(), p.String())
+		}
+		for _, pair := range p.Pairs {
+			key := &object.String{Value: pair.Key.Value}
+			value, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return newError("hash pattern %s: key %q not found", p.String(), pair.Key.Value)
+			}
+			if err := bindPattern(pair.Value, value.Value, env); err != nil {
+				return err
+			}
+		}
+	default:
+		return newError("unsupported pattern: %s", pattern.String())
+	}
+	return nil
+}
+
+// evalLoopBody 对一次循环的循环体求值
+// 返回值不为 nil 时循环应当立即结束 并将返回值作为循环语句的值
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) object.Object {
+	result := Eval(body, env)
+	switch {
+	case result == BREAK:
+		return NULL
+	case result == CONTINUE, result == nil:
+		// 空的循环体求值结果为 nil
+		return nil
+	case isError(result) || result.Type() == object.RETURN_VALUE_OBJ:
+		return result
+	}
+	return nil
+}
+
+// evalRangeExpression 对区间表达式 start..end 求值 区间包含 start 不包含 end
+func evalRangeExpression(left, right object.Object) object.Object {
+	start, ok := left.(*object.Integer)
+	if !ok {
+		return newError("range bounds must be INTEGER, got %s..%s", left.Type(), right.Type())
+	}
+	end, ok := right.(*object.Integer)
+	if !ok {
+		return newError("range bounds must be INTEGER, got %s..%s", left.Type(), right.Type())
+	}
+	return &object.Range{Start: start.Value, End: end.Value}
+}
+
+// loopControlError 在 break 和 continue 离开了循环时报告错误
+// break 和 continue 不能跨越函数调用的边界 在函数体或者程序顶层遇到时说明不在循环中
+func loopControlError(obj object.Object) *object.Error {
+	return newError("%s statement outside loop", obj.Inspect())
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -178,6 +510,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	}
 	return nil
 }
@@ -213,9 +547,20 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	}
 }
 
+// evalTildePrefixOperatorExpression 按位取反表达式
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	v, ok := right.(*object.Integer)
+	if !ok {
+		return newError("unknown operator: ~%s", right.Type())
+	}
+	return object.NewInteger(^v.Value)
+}
+
 // evalInfixExpression 求值中缀表达式
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
+	case operator == "..":
+		return evalRangeExpression(left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case (left.Type() == object.FLOAT_OBJ && right.Type() == object.FLOAT_OBJ) ||
@@ -246,7 +591,42 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return object.NewInteger(leftVal * rightVal)
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / %d", leftVal, rightVal)
+		}
 		return object.NewFloat(float64(leftVal) / float64(rightVal))
+	case "div":
+		if rightVal == 0 {
+			return newError("division by zero: %d div %d", leftVal, rightVal)
+		}
+		return object.NewInteger(floorDiv(leftVal, rightVal))
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero: %d %% %d", leftVal, rightVal)
+		}
+		return object.NewInteger(leftVal - floorDiv(leftVal, rightVal)*rightVal)
+	case "**":
+		if rightVal < 0 {
+			return object.NewFloat(math.Pow(float64(leftVal), float64(rightVal)))
+		}
+		return object.NewInteger(intPow(leftVal, rightVal))
+	case "&":
+		return object.NewInteger(leftVal & rightVal)
+	case "|":
+		return object.NewInteger(leftVal | rightVal)
+	case "^":
+		return object.NewInteger(leftVal ^ rightVal)
+	case "<<":
+		if rightVal < 0 {
+			return newError("negative shift count: %d << %d", leftVal, rightVal)
+		}
+		return object.NewInteger(leftVal << rightVal)
+	case ">>":
+		// 算术右移 负数右移时保留符号位
+		if rightVal < 0 {
+			return newError("negative shift count: %d >> %d", leftVal, rightVal)
+		}
+		return object.NewInteger(leftVal >> rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "<":
@@ -277,7 +657,26 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 	case "*":
 		return object.NewFloat(leftVal * rightVal)
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+		}
 		return object.NewFloat(leftVal / rightVal)
+	case "div":
+		if rightVal == 0 {
+			return newError("division by zero: %s div %s", left.Inspect(), right.Inspect())
+		}
+		return object.NewFloat(math.Floor(leftVal / rightVal))
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+		mod := math.Mod(leftVal, rightVal)
+		if mod != 0 && (mod < 0) != (rightVal < 0) {
+			mod += rightVal
+		}
+		return object.NewFloat(mod)
+	case "**":
+		return object.NewFloat(math.Pow(leftVal, rightVal))
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "<":
@@ -295,6 +694,29 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 	}
 }
 
+// floorDiv 向下取整的整数除法，div 和 % 都基于它计算
+// 因此 % 的结果总是与除数同号，并且满足 (a div b) * b + a % b == a
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// intPow 计算 base 的 exp 次方，exp 不能为负数，溢出时与其他整数运算一样回绕
+func intPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
 func getFloat(o object.Object) float64 {
 	switch v := o.(type) {
 	case *object.Float:
@@ -345,6 +767,33 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	}
 }
 
+// evalLogicalExpression 对 && 和 || 进行短路求值，结果总是布尔值
+// 左操作数已经可以决定结果时不会对右操作数求值
+func evalLogicalExpression(operator string, left object.Object, right ast.Expression, env *object.Environment) object.Object {
+	if isTruthy(left) == (operator == "||") {
+		return nativeBoolToBooleanObject(isTruthy(left))
+	}
+	rVal := Eval(right, env)
+	if isError(rVal) {
+		return rVal
+	}
+	return nativeBoolToBooleanObject(isTruthy(rVal))
+}
+
+// evalInterpolatedString 对插值字符串求值
+// 依次对每个片段求值并使用 Inspect 转换为字符串后拼接
+func evalInterpolatedString(parts []ast.Expression, env *object.Environment) object.Object {
+	var out bytes.Buffer
+	for _, part := range parts {
+		val := Eval(part, env)
+		if isError(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
 func evalIfExpression(condition object.Object, consequence, alternative *ast.BlockStatement, env *object.Environment) object.Object {
 	if isTruthy(condition) {
 		return Eval(consequence, env)
@@ -355,6 +804,124 @@ func evalIfExpression(condition object.Object, consequence, alternative *ast.Blo
 	return Eval(alternative, env)
 }
 
+// evalMatchExpression 对 match 表达式求值
+// 按顺序尝试每个分支 模式匹配并且守卫条件成立时对分支求值
+// 模式中绑定的变量只在对应分支的作用域中可见
+// 没有任何分支匹配时返回错误
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+	for _, arm := range me.Arms {
+		var bindings []matchBinding
+		ok, err := matchPattern(arm.Pattern, subject, env, &bindings)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		armEnv := object.NewEnclosedEnviroment(env)
+		for _, b := range bindings {
+			if err := bindPattern(b.name, b.value, armEnv); err != nil {
+				return err
+			}
+		}
+		if arm.Guard != nil {
+			cond := Eval(arm.Guard, armEnv)
+			if isError(cond) {
+				return cond
+			}
+			if !isTruthy(cond) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+	return newError("non-exhaustive match: no arm matches %s", subject.Inspect())
+}
+
+// matchBinding 模式匹配成功后需要绑定的变量
+type matchBinding struct {
+	name  *ast.Identifier
+	value object.Object
+}
+
+// matchPattern 判断 val 是否匹配 pattern 匹配过程中需要绑定的变量追加到 bindings 中
+// 与 bindPattern 不同 结构不匹配时只返回 false 而不是错误
+// 标识符 _ 是通配符 可以匹配任意值并且不绑定变量
+func matchPattern(pattern ast.Pattern, val object.Object, env *object.Environment, bindings *[]matchBinding) (bool, *object.Error) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if p.Value != "_" {
+			*bindings = append(*bindings, matchBinding{name: p, value: val})
+		}
+		return true, nil
+	case *ast.LiteralPattern:
+		lit := Eval(p.Value, env)
+		if err, ok := lit.(*object.Error); ok {
+			return false, err
+		}
+		return objectsEqual(lit, val), nil
+	case *ast.AlternativePattern:
+		n := len(*bindings)
+		for _, alt := range p.Alternatives {
+			ok, err := matchPattern(alt, val, env, bindings)
+			if err != nil || ok {
+				return ok, err
+			}
+			// 丢弃没有匹配成功的模式中绑定的变量
+			*bindings = (*bindings)[:n]
+		}
+		return false, nil
+	case *ast.ArrayPattern:
+		arr, ok := val.(object.Array)
+		if !ok || len(arr) < len(p.Elements) || (p.Rest == nil && len(arr) != len(p.Elements)) {
+			return false, nil
+		}
+		for i, elem := range p.Elements {
+			if ok, err := matchPattern(elem, arr[i], env, bindings); err != nil || !ok {
+				return ok, err
+			}
+		}
+		if p.Rest != nil {
+			rest := make(object.Array, len(arr)-len(p.Elements))
+			copy(rest, arr[len(p.Elements):])
+			return matchPattern(p.Rest, rest, env, bindings)
+		}
+		return true, nil
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+		for _, pair := range p.Pairs {
+			key := &object.String{Value: pair.Key.Value}
+			value, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return false, nil
+			}
+			if ok, err := matchPattern(pair.Value, value.Value, env, bindings); err != nil || !ok {
+				return ok, err
+			}
+		}
+		return true, nil
+	}
+	return false, newError("unsupported pattern: %s", pattern.String())
+}
+
+// objectsEqual 判断两个值是否相等 类型不同的值总是不相等
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	if ha, ok := a.(object.Hashable); ok {
+		return ha.HashKey() == b.(object.Hashable).HashKey()
+	}
+	return a == b
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -371,7 +938,7 @@ func isTruthy(obj object.Object) bool {
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: object.RUNTIME_ERROR}
 }
 
 // isError 检查求值是否出错
@@ -398,11 +965,23 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 }
 
 // evalExpressions 对多条表达式求值
-// 求值完成后返回对应顺序的值列表
+// 求值完成后返回对应顺序的值列表 展开表达式 ...xs 的值为数组 其元素会被逐个展开到列表中
 // 求值过程一旦发生错误则只会返回错误
 func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.Object {
 	var res []object.Object
 	for _, expr := range exprs {
+		if spread, ok := expr.(*ast.SpreadExpression); ok {
+			val := Eval(spread.Value, env)
+			if isError(val) {
+				return []object.Object{val}
+			}
+			arr, ok := val.(object.Array)
+			if !ok {
+				return []object.Object{newError("cannot spread %s, expected ARRAY", val.Type())}
+			}
+			res = append(res, arr...)
+			continue
+		}
 		val := Eval(expr, env)
 		if isError(val) {
 			return []object.Object{val}
@@ -412,6 +991,94 @@ func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.O
 	return res
 }
 
+// evalArguments 对函数调用的实参列表求值
+// 关键字参数按名字放到 fn 对应形参的位置上 没有传入实参的位置为 nil
+func evalArguments(fn object.Object, exprs []ast.Expression, env *object.Environment) ([]object.Object, *object.Error) {
+	// 语法分析保证关键字参数都位于位置参数之后
+	n := 0
+	for n < len(exprs) {
+		if _, ok := exprs[n].(*ast.KeywordArgument); ok {
+			break
+		}
+		n++
+	}
+	args := evalExpressions(exprs[:n], env)
+	if len(args) != 0 && isError(args[0]) {
+		return nil, args[0].(*object.Error)
+	}
+	if n == len(exprs) {
+		return args, nil
+	}
+
+	names, err := parameterNames(fn)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < len(names) {
+		args = append(args, make([]object.Object, len(names)-len(args))...)
+	}
+	for _, expr := range exprs[n:] {
+		kw := expr.(*ast.KeywordArgument)
+		i := 0
+		for i < len(names) && names[i] != kw.Name.Value {
+			i++
+		}
+		if i == len(names) {
+			return nil, newError("unknown keyword argument %s", kw.Name.Value)
+		}
+		if args[i] != nil {
+			return nil, newError("duplicate argument %s", kw.Name.Value)
+		}
+		val := Eval(kw.Value, env)
+		if isError(val) {
+			return nil, val.(*object.Error)
+		}
+		args[i] = val
+	}
+	// 末尾没有传入的形参交给函数调用时按实参个数处理
+	for len(args) > 0 && args[len(args)-1] == nil {
+		args = args[:len(args)-1]
+	}
+	if _, ok := fn.(*object.Builtin); ok {
+		for i, arg := range args {
+			if arg == nil {
+				return nil, newError("missing argument %s", names[i])
+			}
+		}
+	}
+	return args, nil
+}
+
+// parameterNames 返回可以通过关键字参数传入的形参名
+// 解构模式和可变参数没有名字 对应的位置为空字符串
+func parameterNames(fn object.Object) ([]string, *object.Error) {
+	switch f := fn.(type) {
+	case *object.Builtin:
+		if f.Params == nil {
+			return nil, newError("builtin function does not accept keyword arguments")
+		}
+		return f.Params, nil
+	case *object.Function:
+		var names []string
+		for _, param := range f.Parameters {
+			if dp, ok := param.(*ast.DefaultPattern); ok {
+				param = dp.Pattern
+			}
+			switch p := param.(type) {
+			case *ast.Identifier:
+				names = append(names, p.Value)
+			case *ast.RestPattern:
+			default:
+				names = append(names, "")
+			}
+		}
+		return names, nil
+	case object.BuiltinFunction:
+		return nil, newError("builtin function does not accept keyword arguments")
+	}
+	return nil, newError("not a function: %s", fn.Type())
+}
+
 // applyFunction 对函数调用求值
 // 实现方法是首先对实参列表求值
 // 然后创建新的包裹作用域 上层作用域指向函数申明时的作用域
@@ -421,48 +1088,137 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch f := fn.(type) {
 	case object.BuiltinFunction:
 		return f(args...)
+	case *object.Builtin:
+		return f.Fn(args...)
 	case *object.Function:
-		if len(args) != len(f.Parameters) {
-			return newError("args number mismatch, expect lenght: %d, but got: %d", len(f.Parameters), len(args))
+		if limit := f.Env.MaxCallDepth(); limit > 0 && f.Env.CallDepth() >= limit {
+			return newError("maximum recursion depth %d exceeded", limit)
 		}
+		f.Env.EnterCall()
+		defer f.Env.ExitCall()
 		env := object.NewEnclosedEnviroment(f.Env)
-		for i, param := range f.Parameters {
-			env.Set(param.String(), args[i])
-		}
-		val := evalBlockStatement(f.Body.Statements, env)
-		// 重要：函数调用后应该返回一个解包后的值
-		// 这里不进行解包会导致这个 ReturnValue 向上冒泡
-		// 从而导致上层调用异常提前返回
-		//
-		// (因为设计 ReturnValue 这个类型的初衷是为了感知到多条 statment 执行时该何时返回
-		// 以及避免 return 语句下的语句被执行 所以我们在 BlockStatement 和 Program 等
-		// 涉及多条 statement 执行的地方都对 ReturnValue 进行了判断，并将 ReturnValue
-		// 类型保留继续上抛)
-		//
-		// 直到遇到函数调用的边界就将 ReturnValue 解包得到内层值
-		// 这是因为函数内的 return 不应该直接导致更上层函数的退出
-		// 求值最顶层的语句 Program.Statements 时也解包 ReturnValue 的原因是
-		// 整个程序的返回值应该是一个具体类型的值 而不是包装后的返回值
-		// 假如不考虑程序的返回值 那么对 Program.Statements 求值时不解包 ReturnValue
-		// 也是可以的 具体操作需要看对语言的行为怎么进行定义
-		if retVal, ok := val.(*object.ReturnValue); ok {
-			return retVal.Value
+		if err := bindParameters(f.Parameters, args, env); err != nil {
+			return err
+		}
+		// 函数体以尾调用结束时 在同一个 Go 栈帧中循环执行被调用的函数(蹦床)
+		// 因此尾递归不会加深 Go 的调用栈
+		for {
+			val := evalBlockStatement(f.Body.Statements, env)
+			// 重要：函数调用后应该返回一个解包后的值
+			// 这里不进行解包会导致这个 ReturnValue 向上冒泡
+			// 从而导致上层调用异常提前返回
+			//
+			// (因为设计 ReturnValue 这个类型的初衷是为了感知到多条 statment 执行时该何时返回
+			// 以及避免 return 语句下的语句被执行 所以我们在 BlockStatement 和 Program 等
+			// 涉及多条 statement 执行的地方都对 ReturnValue 进行了判断，并将 ReturnValue
+			// 类型保留继续上抛)
+			//
+			// 直到遇到函数调用的边界就将 ReturnValue 解包得到内层值
+			// 这是因为函数内的 return 不应该直接导致更上层函数的退出
+			// 求值最顶层的语句 Program.Statements 时也解包 ReturnValue 的原因是
+			// 整个程序的返回值应该是一个具体类型的值 而不是包装后的返回值
+			// 假如不考虑程序的返回值 那么对 Program.Statements 求值时不解包 ReturnValue
+			// 也是可以的 具体操作需要看对语言的行为怎么进行定义
+			if rv, ok := val.(*object.ReturnValue); ok {
+				val = rv.Value
+			}
+			switch val := val.(type) {
+			case *object.TailCall:
+				env = object.NewEnclosedEnviroment(val.Function.Env)
+				if err := bindParameters(val.Function.Parameters, val.Arguments, env); err != nil {
+					// 实参与形参不匹配的错误发生在调用方的函数体中
+					if !err.Pos.IsValid() {
+						err.Pos = val.Pos
+					}
+					return addFrame(err, f)
+				}
+				f = val.Function
+				continue
+			case *object.Break, *object.Continue:
+				return loopControlError(val)
+			case *object.Error:
+				return addFrame(val, f)
+			}
+			return val
 		}
-		return val
 	}
 	return newError("not a function: %s", fn.Type())
 }
 
-func evalArrayLiteral(exps []ast.Expression, env *object.Environment) object.Object {
-	arr := object.Array{}
-	for _, exp := range exps {
-		a := Eval(exp, env)
-		if isError(a) {
-			return a
+// addFrame 在错误离开函数时记录调用栈 调用的位置由调用表达式补充
+// 尾调用复用了调用方的栈帧 因此调用栈中只保留最后被调用的函数
+func addFrame(err *object.Error, f *object.Function) *object.Error {
+	name := f.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	err.Stack = append(err.Stack, object.Frame{Function: name})
+	return err
+}
+
+// bindParameters 将实参绑定到函数作用域中对应的形参上
+// 缺少的实参使用形参的默认值 默认值在函数的作用域中求值 因此可以引用排在前面的形参
+// 多余的实参收集到可变参数 ...rest 组成的新数组中
+func bindParameters(params []ast.Pattern, args []object.Object, env *object.Environment) *object.Error {
+	var rest *ast.RestPattern
+	if n := len(params); n > 0 {
+		if rest, _ = params[n-1].(*ast.RestPattern); rest != nil {
+			params = params[:n-1]
+		}
+	}
+	required := 0
+	for _, param := range params {
+		if _, ok := param.(*ast.DefaultPattern); !ok {
+			required++
+		}
+	}
+	switch {
+	case rest == nil && required == len(params) && len(args) != len(params):
+		return newError("args number mismatch, expect lenght: %d, but got: %d", len(params), len(args))
+	case len(args) < required:
+		return newError("args number mismatch, expect at least %d, but got: %d", required, len(args))
+	case rest == nil && len(args) > len(params):
+		return newError("args number mismatch, expect at most %d, but got: %d", len(params), len(args))
+	}
+
+	for i, param := range params {
+		var arg object.Object
+		if i < len(args) {
+			arg = args[i]
+		}
+		if dp, ok := param.(*ast.DefaultPattern); ok {
+			if arg == nil {
+				arg = Eval(dp.Default, env)
+				if err, ok := arg.(*object.Error); ok {
+					return err
+				}
+			}
+			param = dp.Pattern
+		}
+		if arg == nil {
+			// 使用关键字参数调用时 排在前面的形参可能没有传入实参
+			return newError("missing argument %s", param.String())
+		}
+		if err := bindPattern(param, arg, env); err != nil {
+			return err
+		}
+	}
+	if rest != nil {
+		extra := object.Array{}
+		if len(args) > len(params) {
+			extra = append(extra, args[len(params):]...)
 		}
-		arr = append(arr, a)
+		return bindPattern(rest.Name, extra, env)
+	}
+	return nil
+}
+
+func evalArrayLiteral(exps []ast.Expression, env *object.Environment) object.Object {
+	elems := evalExpressions(exps, env)
+	if len(elems) == 1 && isError(elems[0]) {
+		return elems[0]
 	}
-	return arr
+	return append(object.Array{}, elems...)
 }
 
 func evalIndexExpression(left, index object.Object, env *object.Environment) object.Object {
@@ -470,10 +1226,20 @@ func evalIndexExpression(left, index object.Object, env *object.Environment) obj
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		idx := index.(*object.Integer)
 		arr := left.(object.Array)
-		if idx.Value >= int64(len(arr)) || idx.Value < 0 {
-			return NULL
+		i, ok := normalizeIndex(idx.Value, len(arr))
+		if !ok {
+			return indexOutOfRange(idx.Value, len(arr), env)
+		}
+		return arr[i]
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		idx := index.(*object.Integer)
+		str := left.(*object.String)
+		i, ok := normalizeIndex(idx.Value, str.Len())
+		if !ok {
+			return indexOutOfRange(idx.Value, str.Len(), env)
 		}
-		return arr[idx.Value]
+		ch, _ := str.Index(i)
+		return ch
 	case left.Type() == object.HASH_OBJ:
 		hashed, hashable := index.(object.Hashable)
 		if !hashable {
@@ -490,11 +1256,124 @@ func evalIndexExpression(left, index object.Object, env *object.Environment) obj
 	}
 }
 
-func evalHashLiteral(pairs map[ast.Expression]ast.Expression, env *object.Environment) object.Object {
-	hash := &object.Hash{
-		Pairs: map[object.HashKey]object.HashPair{},
+// normalizeIndex 将下标转换为从 0 开始的下标 负数下标从末尾开始计算 -1 表示最后一个元素
+// 下标越界时返回 false
+func normalizeIndex(idx int64, length int) (int, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, false
+	}
+	return int(idx), true
+}
+
+// indexOutOfRange 读取越界时的结果
+// 默认返回 NULL 只有求值使用的最外层作用域开启了 StrictIndexing 时才返回错误
+func indexOutOfRange(idx int64, length int, env *object.Environment) object.Object {
+	if env.StrictIndexing() {
+		return newError("index out of range [%d] with length %d", idx, length)
+	}
+	return NULL
+}
+
+// evalSliceExpression 对切片表达式求值 总是返回新的数组或字符串
+// 字符串按照字符切片 省略的部分为 nil
+func evalSliceExpression(left, start, end, step object.Object) object.Object {
+	switch v := left.(type) {
+	case object.Array:
+		from, to, by, err := sliceBounds(len(v), start, end, step)
+		if err != nil {
+			return err
+		}
+		arr := object.Array{}
+		for i := from; (by > 0 && i < to) || (by < 0 && i > to); i += by {
+			arr = append(arr, v[i])
+		}
+		return arr
+	case *object.String:
+		from, to, by, err := sliceBounds(v.Len(), start, end, step)
+		if err != nil {
+			return err
+		}
+		return v.Slice(from, to, by)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceBounds 计算切片的起止下标和步长 规则与 Python 相同
+// 负数下标从末尾开始计算 越界的下标被截断到合法范围 step 为负数时反向切片
+// 省略 start 和 end 时 根据 step 的方向分别取序列的开头和结尾
+func sliceBounds(length int, start, end, step object.Object) (int, int, int, *object.Error) {
+	bound := func(o object.Object, name string) (int64, bool, *object.Error) {
+		if o == nil {
+			return 0, false, nil
+		}
+		i, ok := o.(*object.Integer)
+		if !ok {
+			return 0, false, newError("slice %s must be INTEGER, got %s", name, o.Type())
+		}
+		return i.Value, true, nil
+	}
+	stepVal, ok, err := bound(step, "step")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if !ok {
+		stepVal = 1
+	}
+	if stepVal == 0 {
+		return 0, 0, 0, newError("slice step cannot be zero")
+	}
+
+	// step 为负数时 -1 表示第一个元素之前的位置
+	lower, upper := int64(0), int64(length)
+	if stepVal < 0 {
+		lower, upper = -1, int64(length)-1
+	}
+	clamp := func(o object.Object, name string, def int64) (int64, *object.Error) {
+		i, ok, err := bound(o, name)
+		if err != nil || !ok {
+			return def, err
+		}
+		if i < 0 {
+			i += int64(length)
+			if i < lower {
+				i = lower
+			}
+		} else if i > upper {
+			i = upper
+		}
+		return i, nil
+	}
+	defStart, defEnd := lower, upper
+	if stepVal < 0 {
+		defStart, defEnd = upper, lower
+	}
+	startVal, err := clamp(start, "start", defStart)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	endVal, err := clamp(end, "end", defEnd)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	// start 和 end 都已经在 [-1, length] 范围内 步长的绝对值超过 length 时最多只能取到一个元素
+	// 截断步长 避免按步长计算下一个下标时溢出
+	if stepVal > int64(length) {
+		stepVal = int64(length) + 1
+	} else if stepVal < -int64(length) {
+		stepVal = -int64(length) - 1
 	}
-	for k, v := range pairs {
+	return int(startVal), int(endVal), int(stepVal), nil
+}
+
+// evalHashLiteral 按照键在源码中出现的顺序对哈希表字面量求值
+func evalHashLiteral(hl *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	for _, k := range hl.Keys {
 		key := Eval(k, env)
 		if isError(key) {
 			return key
@@ -504,54 +1383,167 @@ func evalHashLiteral(pairs map[ast.Expression]ast.Expression, env *object.Enviro
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(v, env)
+		value := Eval(hl.Pairs[k], env)
 		if isError(value) {
 			return value
 		}
-		hash.Pairs[hk.HashKey()] = object.HashPair{
+		hash.Set(hk.HashKey(), object.HashPair{
 			Key:   key,
 			Value: value,
-		}
+		})
 	}
 	return hash
 }
 
-func evalAssignExpression(left ast.Expression, val object.Object, env *object.Environment) object.Object {
+// evalAssignExpression 对赋值表达式求值 结果为赋给目标的值 后缀自增自减的结果为修改之前的值
+// 前缀自增自减的结果为修改之后的值
+// 复合赋值 a[f()] += 1 只会对目标中的子表达式 a 和 f() 求值一次
+func evalAssignExpression(ae *ast.AssignExpression, env *object.Environment) object.Object {
+	target := evalAssignTarget(ae.Left, env)
+	if isError(target.err) {
+		return target.err
+	}
+	if ae.Operator == "=" {
+		val := Eval(ae.Value, env)
+		if isError(val) {
+			return val
+		}
+		return target.set(val, env)
+	}
+
+	cur := target.get(env)
+	if isError(cur) {
+		return cur
+	}
+	switch ae.Operator {
+	case "++", "--":
+		val := evalInfixExpression(ae.Operator[:1], cur, object.NewInteger(1))
+		if isError(val) {
+			return val
+		}
+		if res := target.set(val, env); isError(res) {
+			return res
+		}
+		if ae.Prefix {
+			return val
+		}
+		return cur
+	default:
+		// += -= *= /= %= 去掉 = 即为对应的中缀运算符
+		right := Eval(ae.Value, env)
+		if isError(right) {
+			return right
+		}
+		val := evalInfixExpression(strings.TrimSuffix(ae.Operator, "="), cur, right)
+		if isError(val) {
+			return val
+		}
+		return target.set(val, env)
+	}
+}
+
+// assignTarget 赋值的目标 目标中的子表达式已经求值完成
+type assignTarget struct {
+	name      string        // 被赋值的标识符或者成员的名字
+	container object.Object // 下标赋值和成员赋值时被赋值的对象 对标识符赋值时为 nil
+	index     object.Object // 下标赋值时的下标 其余情况为 nil
+	err       object.Object // 对目标求值时产生的错误
+}
+
+// evalAssignTarget 对赋值目标中的子表达式求值
+func evalAssignTarget(left ast.Expression, env *object.Environment) *assignTarget {
 	switch v := left.(type) {
 	case *ast.Identifier:
-		// 对标识符赋值
-		return env.Assign(v.Value, val)
+		return &assignTarget{name: v.Value}
 	case *ast.IndexExpression:
+		container := Eval(v.Left, env)
+		if isError(container) {
+			return &assignTarget{err: container}
+		}
 		index := Eval(v.Index, env)
 		if isError(index) {
-			return index
+			return &assignTarget{err: index}
 		}
-		left := Eval(v.Left, env)
-		if isError(left) {
-			return left
+		return &assignTarget{container: container, index: index}
+	case *ast.MemberExpression:
+		container := Eval(v.Object, env)
+		if isError(container) {
+			return &assignTarget{err: container}
 		}
-		switch {
-		case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-			// 对数组赋值
-			arr := left.(object.Array)
-			idx := index.(*object.Integer)
-			if idx.Value >= int64(len(arr)) {
-				return NULL
-			}
-			arr[idx.Value] = val
-			return val
-		case left.Type() == object.HASH_OBJ:
-			// 对 map 赋值
-			hash := left.(*object.Hash)
-			idx := index.(object.Hashable)
-			hash.Pairs[idx.HashKey()] = object.HashPair{
-				Key:   index,
-				Value: val,
+		return &assignTarget{name: v.Member.Value, container: container}
+	}
+	return &assignTarget{err: newError("unable to assign to expression: %s", left.String())}
+}
+
+// get 读取目标当前的值 用于复合赋值
+func (t *assignTarget) get(env *object.Environment) object.Object {
+	switch {
+	case t.container == nil:
+		val, ok := env.Get(t.name)
+		if !ok {
+			return newError("identifier not found: " + t.name)
+		}
+		return val
+	case t.index == nil:
+		if _, ok := t.container.(*object.Hash); !ok {
+			return newError("cannot assign to member %s of %s", t.name, t.container.Type())
+		}
+		return evalMemberExpression(t.container, t.name)
+	}
+	// 复合赋值时数组越界总是报错
+	if arr, ok := t.container.(object.Array); ok {
+		if idx, ok := t.index.(*object.Integer); ok {
+			if _, ok := normalizeIndex(idx.Value, len(arr)); !ok {
+				return newError("index out of range [%d] with length %d", idx.Value, len(arr))
 			}
-			return val
-		default:
-			return newError("index operator not supported: %s", left.Type())
 		}
 	}
-	return newError("unable to assign Object: %s to expression", val.Type())
+	return evalIndexExpression(t.container, t.index, env)
+}
+
+// set 将 val 赋给目标 成功时返回 val
+func (t *assignTarget) set(val object.Object, env *object.Environment) object.Object {
+	switch {
+	case t.container == nil:
+		// 对标识符赋值
+		return env.Assign(t.name, val)
+	case t.index == nil:
+		// 对哈希表的字符串键赋值
+		hash, ok := t.container.(*object.Hash)
+		if !ok {
+			return newError("cannot assign to member %s of %s", t.name, t.container.Type())
+		}
+		key := &object.String{Value: t.name}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: val})
+		return val
+	}
+	switch container := t.container.(type) {
+	case object.Array:
+		// 对数组赋值
+		idx, ok := t.index.(*object.Integer)
+		if !ok {
+			return newError("index operator not supported: %s", container.Type())
+		}
+		i, ok := normalizeIndex(idx.Value, len(container))
+		if !ok {
+			return newError("index out of range [%d] with length %d", idx.Value, len(container))
+		}
+		container[i] = val
+		return val
+	case *object.Hash:
+		// 对 map 赋值
+		key, ok := t.index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", t.index.Type())
+		}
+		container.Set(key.HashKey(), object.HashPair{
+			Key:   t.index,
+			Value: val,
+		})
+		return val
+	case *object.String:
+		// 字符串不可变 只能读取下标
+		return newError("cannot assign to index of %s", container.Type())
+	}
+	return newError("index operator not supported: %s", t.container.Type())
 }
@@ -4,6 +4,9 @@ import (
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"reflect"
+	"runtime/debug"
+	"sync"
 	"testing"
 )
 
@@ -15,6 +18,27 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+// testEvalIn 在 env 中求值 用于需要设置求值选项的测试
+func testEvalIn(input string, env *object.Environment) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	return Eval(program, env)
+}
+
+// mustEval 与 testEval 相同 但是源码有语法错误时直接让测试失败
+func mustEval(t *testing.T, input string) object.Object {
+	t.Helper()
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		t.Fatalf("parser has %d errors for %q: %q", len(errors), input, errors)
+	}
+	env := object.NewEnvironment()
+	return Eval(program, env)
+}
+
 func TestEvalIntegerExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -36,6 +60,38 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", int64(37)},
 		{"3 * (3 * 3) + 10", int64(37)},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", float64(50)},
+		{"0xff + 0b1010 + 0o17", int64(280)},
+		{"1_000_000", int64(1000000)},
+		{".5 + 1e1", float64(10.5)},
+		{"2.5E-1", float64(0.25)},
+		{"7 div 2", int64(3)},
+		{"-7 div 2", int64(-4)},
+		{"7 div -2", int64(-4)},
+		{"7 % 3", int64(1)},
+		{"-7 % 3", int64(2)},
+		{"7 % -3", int64(-2)},
+		{"-7 % -3", int64(-1)},
+		{"(-7 div 3) * 3 + -7 % 3", int64(-7)},
+		{"2 ** 10", int64(1024)},
+		{"2 ** 3 ** 2", int64(512)},
+		{"-2 ** 2", int64(-4)},
+		{"(-2) ** 3", int64(-8)},
+		{"2 ** 0", int64(1)},
+		{"2 ** -1", float64(0.5)},
+		{"2 * 3 ** 2", int64(18)},
+		{"7.5 div 2", float64(3)},
+		{"-7.5 % 2", float64(0.5)},
+		{"2.0 ** 0.5 ** 2", float64(1.189207115002721)},
+		{"0b1100 & 0b1010", int64(0b1000)},
+		{"0b1100 | 0b1010", int64(0b1110)},
+		{"0b1100 ^ 0b1010", int64(0b0110)},
+		{"~0", int64(-1)},
+		{"~5", int64(-6)},
+		{"1 << 10", int64(1024)},
+		{"1 << 64", int64(0)},
+		{"1024 >> 3", int64(128)},
+		{"-16 >> 2", int64(-4)},
+		{"0xff & 0xf0 >> 4", int64(0x0f)},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -123,6 +179,15 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{`"a" == "a"`, true},
 		{`"a" >= "b"`, false},
 		{`"a" <= "b"`, true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"1 && 0", false},
+		{"1 | 2 == 3", true},
+		{"6 & 3 != 2", false},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -130,6 +195,29 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestLogicalShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		// 右操作数会出错 只有被求值时才会返回错误
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"let x = 0; let f = fn() { x = 1; true }; false && f(); x == 0", true},
+		{"let x = 0; let f = fn() { x = 1; true }; true && f(); x == 1", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("true && undefined")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: undefined" {
+		t.Fatalf("expected error for evaluated right operand. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -168,6 +256,30 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "monkey"; "hello ${name}"`, "hello monkey"},
+		{`let age = 3; "you are ${age + 1}"`, "you are 4"},
+		{`"${1.5} ${true} ${[1, "a"]}"`, "1.500000 true [1, a]"},
+		{`let f = fn(x) { "<${x}>" }; "${f("a")}${f("b")}"`, "<a><b>"},
+		{`"\${age}"`, "${age}"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
 func TestStringComparation(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -309,6 +421,10 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			`"hello ${foobar}"`,
+			"identifier not found: foobar",
+		},
 		{
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
@@ -317,6 +433,254 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"1 << -1",
+			"negative shift count: 1 << -1",
+		},
+		{
+			"1 >> -2",
+			"negative shift count: 1 >> -2",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"x += 1",
+			"identifier not found: x",
+		},
+		{
+			"5 += 1",
+			"unable to assign to expression: 5",
+		},
+		{
+			"++5",
+			"unable to assign to expression: 5",
+		},
+		{
+			"--y",
+			"identifier not found: y",
+		},
+		{
+			`let s = "a"; s -= 1`,
+			"type mismatch: STRING - INTEGER",
+		},
+		{
+			"let x = 1; x /= 0",
+			"division by zero: 1 / 0",
+		},
+		{
+			"1 / 0",
+			"division by zero: 1 / 0",
+		},
+		{
+			"5 div 0",
+			"division by zero: 5 div 0",
+		},
+		{
+			"5 % 0",
+			"division by zero: 5 % 0",
+		},
+		{
+			"1.5 / 0",
+			"division by zero: 1.500000 / 0",
+		},
+		{
+			"1 % 0.0",
+			"division by zero: 1 % 0.000000",
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { i = i + 1; }; i", 10},
+		{"let i = 0; while (false) { i = i + 1; }; i", 0},
+		{"let i = 0; while (true) { i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let i = 0; let sum = 0; while (i < 10) { i = i + 1; if (i % 2 == 0) { continue; } sum = sum + i; }; sum", 25},
+		// break 只跳出最内层的循环
+		{"let n = 0; let i = 0; while (i < 3) { i = i + 1; let j = 0; while (true) { j = j + 1; n = n + 1; if (j == 2) { break; } } }; n", 6},
+		// 函数内的循环 return 直接结束函数
+		{"let f = fn() { let i = 0; while (true) { i = i + 1; if (i == 3) { return i * 10; } } }; f()", 30},
+		// 每次循环的块级作用域是独立的
+		{"let i = 0; while (i < 3) { let x = i; i = i + 1; }; i", 3},
+		{"while (false) { 1 }", nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum = sum + x; }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { sum = sum + i * x; }; sum", 80},
+		{"let sum = 0; for (i in 0..5) { sum = sum + i; }; sum", 10},
+		{"let sum = 0; for (i in 3..1) { sum = sum + 1; }; sum", 0},
+		{`let s = ""; for (k in {"a": 1, "b": 2, "c": 3}) { s = s + k; }; s`, "abc"},
+		{`let s = ""; for (k, v in {"b": 1, "a": 2}) { s = s + k + "${v}"; }; s`, "b1a2"},
+		{`let s = ""; for (ch in "héllo") { s = ch + s; }; s`, "olléh"},
+		{`let n = 0; for (i, ch in "日本") { n = n + i; }; n`, 1},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } n = n + x; }; n", 3},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue; } n = n + x; }; n", 4},
+		{"let f = fn() { for (x in 0..100) { if (x * x > 50) { return x; } } }; f()", 8},
+		// 每次循环都有独立的循环变量 闭包捕获的是本次循环的值
+		{"let fs = [0, 0, 0]; for (i in 0..3) { fs[i] = fn() { i }; }; fs[0]() + fs[2]()", 2},
+		{"for (x in []) { x }", nil},
+		{"for (x in 0..3) { }", nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"for (x in 5) { x }", "INTEGER is not iterable"},
+		{`for (x in 0.."a") { x }`, "range bounds must be INTEGER, got INTEGER..STRING"},
+		{"for (x in [1]) { y }", "identifier not found: y"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let user = {"name": "Monkey", "age": 3}; user.name`, "Monkey"},
+		{`let user = {"name": "Monkey", "age": 3}; user.age = user.age + 1; user["age"]`, 4},
+		{`let user = {}; user.name = "Monkey"; user.name`, "Monkey"},
+		{`let a = {"b": {"c": 5}}; a.b.c`, 5},
+		{`let h = {}; h.missing`, nil},
+		// 同名的键优先于方法
+		{`let h = {"keys": 1}; h.keys`, 1},
+		{`"abc".upper()`, "ABC"},
+		{`"ABC".lower()`, "abc"},
+		{`"  abc  ".trim()`, "abc"},
+		{`"héllo".len()`, 5},
+		{`"a,b,c".split(",").len()`, 3},
+		{`"a-b-c".replace("-", "+")`, "a+b+c"},
+		{`"monkey".startsWith("mon") && "monkey".endsWith("key") && "monkey".contains("nk")`, true},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).join(",")`, "2,4,6"},
+		{`[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 }).len()`, 2},
+		{`[1, 2, 3, 4].reduce(fn(acc, x) { acc + x }, 0)`, 10},
+		{`let xs = [1, 2]; let ys = xs.push(3); xs.len() * 10 + ys.last()`, 23},
+		{`[].first()`, nil},
+		{`{"b": 1, "a": 2}.keys().join(" ")`, "b a"},
+		{`{"b": 1, "a": 2}.values().reduce(fn(a, b) { a + b }, 0)`, 3},
+		{`{"b": 1}.has("b")`, true},
+		// 方法可以作为函数值传递
+		{`let up = "abc".upper; up()`, "ABC"},
+		{`["a", "b"].map(fn(s) { s.upper() }).join("")`, "AB"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`"abc".missing()`, "undefined method missing for STRING"},
+		{`5.len`, "undefined method len for INTEGER"},
+		{`let x = 5; x.y = 1`, "cannot assign to member y of INTEGER"},
+		{`"abc".split(1)`, "argument to `split` must be STRING, got INTEGER"},
+		{`"abc".upper(1)`, "wrong number of arguments. got=1, want=0"},
+		{`[1].map(fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	input := `let h = {"z": 1, "a": 2, 3: 3}; h["m"] = 4; h["z"] = 5; h`
+	evaluated := testEval(input)
+	expected := `{z: 5, a: 2, 3: 3, m: 4}`
+	if evaluated.Inspect() != expected {
+		t.Errorf("hash has wrong order. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"break;", "break statement outside loop"},
+		{"if (true) { continue; }", "continue statement outside loop"},
+		// break 不能穿过函数调用的边界
+		{"let f = fn() { break; }; while (true) { f(); }", "break statement outside loop"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -346,6 +710,534 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; a + b", "3"},
+		{"let [a, ...rest] = [1, 2, 3]; rest", "[2, 3]"},
+		{"let [a, b, ...rest] = [1, 2]; rest", "[]"},
+		{"let [a, [b, c]] = [1, [2, 3]]; a * 100 + b * 10 + c", "123"},
+		{`let user = {"name": "Monkey", "age": 3}; let {name, age: years} = user; name + " " + "${years}"`, "Monkey 3"},
+		{`let {pos: [x, y]} = {"pos": [3, 4], "other": 1}; x * y`, "12"},
+		{"let xs = [1, 2, 3]; let [...copy] = xs; copy[0] = 10; xs", "[1, 2, 3]"},
+		{"let f = fn([a, b], {c}) { a + b + c }; f([1, 2], {\"c\": 3})", "6"},
+		{"fn head([first, ...rest]) { first }; head([7, 8, 9])", "7"},
+		{"let pairs = [[1, 2], [3, 4]]; pairs.map(fn([a, b]) { a * b })", "[2, 12]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let [a, b] = [1];", "array pattern [a, b] expects 2 elements, got 1"},
+		{"let [a, b] = [1, 2, 3];", "array pattern [a, b] expects 2 elements, got 3"},
+		{"let [a, b, ...c] = [1];", "array pattern [a, b, ...c] expects at least 2 elements, got 1"},
+		{"let [a] = 5;", "cannot destructure INTEGER as array: [a]"},
+		{"let {a} = [1];", "cannot destructure ARRAY as hash: {a}"},
+		{`let {name, age} = {"name": "x"};`, `hash pattern {name, age}: key "age" not found`},
+		{"let a = 1; let [a] = [2];", "identifier exist: a"},
+		{"let f = fn([a, b]) { a }; f([1])", "array pattern [a, b] expects 2 elements, got 1"},
+		{"let f = fn(a, a) { a }; f(1, 2)", "identifier exist: a"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestDefaultRestAndSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b = 2) { a + b }; f(1)", "3"},
+		{"let f = fn(a, b = 2) { a + b }; f(1, 10)", "11"},
+		{"let f = fn(a, b = a * 2) { b }; f(5)", "10"},
+		{"let x = 1; let f = fn(a = x) { a }; let x2 = 5; f()", "1"},
+		{"let f = fn(a, ...rest) { rest }; f(1)", "[]"},
+		{"let f = fn(a, ...rest) { rest }; f(1, 2, 3)", "[2, 3]"},
+		{"let f = fn(a, b = 0, ...rest) { [a, b, rest] }; f(1, 2, 3, 4)", "[1, 2, [3, 4]]"},
+		{"fn sum(...xs) { xs.reduce(fn(acc, x) { acc + x }, 0) }; sum(1, 2, 3)", "6"},
+		{"let add = fn(a, b, c) { a + b + c }; let xs = [1, 2, 3]; add(...xs)", "6"},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2], 3)", "6"},
+		{"let xs = [2, 3]; [1, ...xs, 4]", "[1, 2, 3, 4]"},
+		{"[...[], ...[1], ...[]]", "[1]"},
+		{"let xs = [1]; let ys = [...xs]; ys[0] = 2; xs", "[1]"},
+		{"let f = fn([a, b] = [1, 2]) { a + b }; f()", "3"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let f = fn(a, b) { a }; f(1)", "args number mismatch, expect lenght: 2, but got: 1"},
+		{"let f = fn(a, b = 2) { a }; f()", "args number mismatch, expect at least 1, but got: 0"},
+		{"let f = fn(a, b = 2) { a }; f(1, 2, 3)", "args number mismatch, expect at most 2, but got: 3"},
+		{"let f = fn(a, ...rest) { a }; f()", "args number mismatch, expect at least 1, but got: 0"},
+		{"let f = fn(a = missing) { a }; f()", "identifier not found: missing"},
+		{"let f = fn(a) { a }; f(...5)", "cannot spread INTEGER, expected ARRAY"},
+		{`[1, ..."ab"]`, "cannot spread STRING, expected ARRAY"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let connect = fn(host, port) { host + ":" + "${port}" }; connect(port: 5432, host: "db")`, "db:5432"},
+		{`let connect = fn(host, port = 5432) { host + ":" + "${port}" }; connect(host: "db")`, "db:5432"},
+		{"let f = fn(a, b = 2, c = 3) { [a, b, c] }; f(1, c: 30)", "[1, 2, 30]"},
+		{"let f = fn(a, b = a + 1) { [a, b] }; f(a: 5)", "[5, 6]"},
+		{"let f = fn(a, ...rest) { [a, rest] }; f(a: 1)", "[1, []]"},
+		{"let f = fn([x, y], z) { x + y + z }; f([1, 2], z: 3)", "6"},
+		{"len(value: [1, 2, 3])", "3"},
+		{"push([1], elem: 2)", "[1, 2]"},
+		{"push(elem: 2, array: [1])", "[1, 2]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let f = fn(a, b) { a }; f(1, c: 2)", "unknown keyword argument c"},
+		{"let f = fn(a, b) { a }; f(1, a: 2)", "duplicate argument a"},
+		{"let f = fn(a, b) { a }; f(b: 1, b: 2)", "duplicate argument b"},
+		{"let f = fn(a, b) { a }; f(b: 2)", "missing argument a"},
+		{"let f = fn(a, ...rest) { a }; f(1, 2, rest: 3)", "unknown keyword argument rest"},
+		{"push(elem: 1)", "missing argument array"},
+		{"puts(value: 1)", "builtin function does not accept keyword arguments"},
+		{`"abc".contains(s: "a")`, "builtin function does not accept keyword arguments"},
+		{"let f = fn(a) { a }; f(a: missing)", "identifier not found: missing"},
+		{"5(a: 1)", "not a function: INTEGER"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	classify := `let classify = fn(v) {
+		match (v) {
+			0 => "zero",
+			1 | 2 | 3 => "small",
+			-1 => "minus one",
+			"hi" => "greeting",
+			true | false => "bool",
+			[] => "empty",
+			[x] => "one ${x}",
+			[1, y] => "starts with one then ${y}",
+			[x, y] => "pair ${x + y}",
+			[first, ...rest] => "list ${first} ${len(rest)}",
+			{kind: "circle", r} => "circle ${r}",
+			{kind} => "shape ${kind}",
+			_ => "other"
+		}
+	};`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "zero"},
+		{"2", "small"},
+		{"-1", "minus one"},
+		{"50", "other"},
+		{`"hi"`, "greeting"},
+		{`"ho"`, "other"},
+		{"false", "bool"},
+		{"[]", "empty"},
+		{"[7]", "one 7"},
+		{"[1, 5]", "starts with one then 5"},
+		{"[2, 5]", "pair 7"},
+		{"[1, 2, 3]", "list 1 2"},
+		{`{"kind": "circle", "r": 2}`, "circle 2"},
+		{`{"kind": "square"}`, "shape square"},
+		{`{"r": 2}`, "other"},
+		{"1.5", "other"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(classify + "classify(" + tt.input + ")")
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	other := []struct {
+		input    string
+		expected string
+	}{
+		// 分支中绑定的变量只在分支内可见
+		{"let x = 1; match (2) { x => x }; x", "1"},
+		{"match (101) { n if n > 100 => n * 2, n => n }", "202"},
+		{"match (99) { n if n > 100 => n * 2, n => n }", "99"},
+		{"match ([[1, 2], 3]) { [[a, b], c] => a + b + c }", "6"},
+		{"match ([0, 5]) { [0, y] | [y, 0] => y }", "5"},
+		{"match ([5, 0]) { [0, y] | [y, 0] => y }", "5"},
+		{"let f = fn(x) { match (x) { 1 => { return 10; } _ => 0 }; 20 }; f(1)", "10"},
+		{"let n = 0; for (i in 0..5) { match (i) { 2 => { continue; } _ => { n += i } } }; n", "8"},
+		{`match (1) { 1 => {"a": 1} }`, "{a: 1}"},
+		{`let k = "b"; match (2) { 1 => {"a": 1}, n => {k: n * 2}["b"] }`, "4"},
+	}
+	for _, tt := range other {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"match (5) { 1 => 1, 2 => 2 }", "non-exhaustive match: no arm matches 5"},
+		{"match ([1]) { [x] if x > 1 => x }", "non-exhaustive match: no arm matches [1]"},
+		{"match ([1, 2]) { [x, x] => x }", "identifier exist: x"},
+		{"match (missing) { _ => 1 }", "identifier not found: missing"},
+		{"match (1) { x if x + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { throw "boom"; } catch (e) { e.message }`, "boom"},
+		{`try { throw "boom"; } catch (e) { e.kind }`, "Error"},
+		{`try { throw {"kind": "ValueError", "message": "bad"}; } catch (e) { e.kind + ": " + e.message }`, "ValueError: bad"},
+		{`try { throw 42; } catch (e) { e.message }`, "42"},
+		{"try { 1 + true } catch (e) { e.message }", "type mismatch: INTEGER + BOOLEAN"},
+		{"try { 1 + true } catch (e) { e.kind }", "RuntimeError"},
+		{"try { y = 1 } catch (e) { e.kind }", "RuntimeError"},
+		{"try {\n  1 + true\n} catch (e) { e.position }", "2:3"},
+		{"let f = fn(a) { a }; try { f(1, 2) } catch (e) { e.message }", "args number mismatch, expect lenght: 1, but got: 2"},
+		{`try { len(1, 2) } catch (e) { e.message }`, "wrong number of arguments. got=2, want=1"},
+		{"try { 1 } catch (e) { 2 }", "1"},
+		{"try { 1 } catch { 2 }", "1"},
+		{"try { missing } catch { 2 }", "2"},
+		{"let x = 0; try { x = 1 } finally { x = 2 }; x", "2"},
+		{"let x = 0; try { throw 1 } catch { x += 1 } finally { x *= 10 }; x", "10"},
+		{"try { try { throw \"inner\" } finally { 1 } } catch (e) { e.message }", "inner"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e.message }`, "a"},
+		{`try { try { throw "a" } catch (e) { throw "b" } } catch (e) { e.message }`, "b"},
+		{"let e = 1; try { throw 2 } catch (e) { e }; e", "1"},
+		// return break continue 穿过 finally 继续传递
+		{"let log = []; let f = fn() { try { return 1; } finally { log = log.push(2) } }; [f(), log]", "[1, [2]]"},
+		{"let f = fn() { try { throw 1; } catch { return 2; } finally { 3 } }; f()", "2"},
+		{"let f = fn() { try { return 1; } finally { return 2; } }; f()", "2"},
+		{"let f = fn() { try { throw 1; } finally { return 2; } }; f()", "2"},
+		{"let n = 0; for (i in 0..10) { try { if (i == 3) { break; } } finally { n += 1 } }; n", "4"},
+		{"let n = 0; for (i in 0..5) { try { if (i % 2 == 0) { continue; } n += 10 } finally { n += 1 } }; n", "25"},
+		{"let n = 0; while (true) { try { n += 1; if (n == 3) { break; } } catch { } }; n", "3"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+		expectedKind    string
+	}{
+		{`throw "boom"`, "boom", "Error"},
+		{`try { throw "a" } finally { 1 }`, "a", "Error"},
+		{`try { 1 } finally { throw "f" }`, "f", "Error"},
+		{`try { throw "a" } catch { throw "b" } finally { 1 }`, "b", "Error"},
+		{`throw {"kind": "IOError", "message": "closed"}`, "closed", "IOError"},
+		{"try { 1 } catch (e) { 2 } finally { missing }", "identifier not found: missing", "RuntimeError"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind for %q. expected=%q, got=%q", tt.input, tt.expectedKind, errObj.Kind)
+		}
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "1:1"},
+		{"let x = 1;\nlet y = x + missing;", "2:13"},
+		{"let f = fn() {\n  throw \"x\"\n};\nf()", "2:3"},
+		{"[1, 2].map(fn(x) { x / 0 })", "1:20"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expected {
+			t.Errorf("wrong error position for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Pos.String())
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // 函数名 调用位置
+	}{
+		{"1 + true", nil},
+		{
+			"fn inner() {\n  x\n}\nfn outer() {\n  let r = inner();\n  r\n}\nouter()",
+			[]string{"inner 5:11", "outer 8:1"},
+		},
+		{
+			"let f = fn() { missing };\n[1].map(fn(x) { f() + 1 })",
+			[]string{"<anonymous> 2:17", "<anonymous> 2:1"},
+		},
+		// 尾调用复用调用方的栈帧 调用栈中只保留最后被调用的函数
+		{
+			"fn inner() {\n  x\n}\nfn outer() {\n  inner()\n}\nouter()",
+			[]string{"inner 7:1"},
+		},
+		// 实参个数错误发生在调用处 不记录被调用的函数
+		{"fn f(a) { a }\nf()", nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		var stack []string
+		for _, frame := range errObj.Stack {
+			stack = append(stack, frame.Function+" "+frame.Pos.String())
+		}
+		if !reflect.DeepEqual(stack, tt.expected) {
+			t.Errorf("wrong stack for %q. expected=%q, got=%q", tt.input, tt.expected, stack)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// 限制 Go 栈的大小 尾递归没有被优化时会因为栈溢出而崩溃
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn loop(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + 1) } }; loop(1000000, 0)", "1000000"},
+		{`fn count(n) { if (n == 0) { return "done"; } return count(n - 1); }; count(100000)`, "done"},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(100001)", "false"},
+		{"fn m(n) { match (n) { 0 => 0, _ => m(n - 1) } }; m(100000)", "0"},
+		{"fn w(n) { while (true) { return if (n == 0) { 0 } else { w(n - 1) }; } }; w(100000)", "0"},
+		// try 中的调用不是尾调用 错误需要被 catch 捕获
+		{`fn f() { try { g() } catch (e) { e.message } }; fn g() { throw "x" }; f()`, "x"},
+		// 不在尾部位置的调用结果不受影响
+		{"fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(10)", "3628800"},
+		{"let f = fn(x) { x * 2 }; let g = fn(x) { f(x) }; g(21)", "42"},
+		{"let g = fn() { len([1, 2]) }; g()", "2"},
+		{"let g = fn(...xs) { len(xs) }; let f = fn(n) { g(n, n) }; f(1)", "2"},
+	}
+	for _, tt := range tests {
+		evaluated := mustEval(t, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"fn f(a) { a }; fn g() { f() }; g()", "args number mismatch, expect lenght: 1, but got: 0"},
+		{"fn loop(n) { if (n == 0) { missing } else { loop(n - 1) } }; loop(100000)", "identifier not found: missing"},
+	}
+	for _, tt := range errTests {
+		evaluated := mustEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestRecursionDepthLimit(t *testing.T) {
+	tests := []struct {
+		maxDepth        int
+		input           string
+		expectedMessage string
+	}{
+		{10000, "fn f(n) { 1 + f(n + 1) }; f(0)", "maximum recursion depth 10000 exceeded"},
+		{10000, "let even = fn(n) { if (n == 0) { true } else { !odd(n - 1) } }; let odd = fn(n) { even(n) }; even(20000)", "maximum recursion depth 10000 exceeded"},
+		{50, "fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(100)", "maximum recursion depth 50 exceeded"},
+	}
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.SetMaxCallDepth(tt.maxDepth)
+		evaluated := testEvalIn(tt.input, env)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+
+	results := []struct {
+		maxDepth int
+		input    string
+		expected string
+	}{
+		{10000, "fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(9999)", "9999"},
+		{50, "fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(49)", "49"},
+		{0, "fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(20000)", "20000"},
+		// 尾调用不会增加调用深度
+		{50, "fn f(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)", "0"},
+		// 超出深度的错误可以被捕获 捕获之后可以继续正常调用
+		{10000, "fn f(n) { 1 + f(n + 1) }; let m = \"\"; try { f(0) } catch (e) { m = e.message }; fn g() { 1 }; [m, g()]", "[maximum recursion depth 10000 exceeded, 1]"},
+	}
+	for _, tt := range results {
+		env := object.NewEnvironment()
+		env.SetMaxCallDepth(tt.maxDepth)
+		evaluated := testEvalIn(tt.input, env)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRecursionDepthPerEvaluation(t *testing.T) {
+	eval := func(input string, env *object.Environment) string {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("parser has %d errors for %q: %q", len(errors), input, errors)
+		}
+		return Eval(program, env).Inspect()
+	}
+	def := "fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }"
+
+	if got := object.NewEnvironment().MaxCallDepth(); got != object.DefaultMaxCallDepth {
+		t.Errorf("wrong default max call depth. expected=%d, got=%d", object.DefaultMaxCallDepth, got)
+	}
+
+	// 超出深度返回错误之后 同一个作用域中的调用深度恢复为 0
+	env := object.NewEnvironment()
+	env.SetMaxCallDepth(100)
+	eval(def, env)
+	if got := eval("f(200)", env); got != "ERROR: maximum recursion depth 100 exceeded" {
+		t.Errorf("wrong result for f(200). got=%q", got)
+	}
+	if got := eval("f(99)", env); got != "99" {
+		t.Errorf("wrong result for f(99) after error. got=%q", got)
+	}
+
+	// 在内层作用域中设置的限制对整个求值生效
+	env = object.NewEnvironment()
+	object.NewEnclosedEnviroment(env).SetMaxCallDepth(10)
+	eval(def, env)
+	if got := eval("f(20)", env); got != "ERROR: maximum recursion depth 10 exceeded" {
+		t.Errorf("wrong result for f(20) with limit set on enclosed scope. got=%q", got)
+	}
+
+	// 不同的求值各自记录调用深度和深度限制 同时求值时互不影响
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env := object.NewEnvironment()
+			env.SetMaxCallDepth(50 + 50*(i%2))
+			eval(def, env)
+			results[i] = eval("let sum = 0; for (i in 0..50) { sum += f(99) }; sum", env)
+		}(i)
+	}
+	wg.Wait()
+	for i, got := range results {
+		expected := "4950"
+		if i%2 == 0 {
+			expected = "ERROR: maximum recursion depth 50 exceeded"
+		}
+		if got != expected {
+			t.Errorf("wrong result for goroutine %d. expected=%q, got=%q", i, expected, got)
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 	evaluated := testEval(input)
@@ -458,6 +1350,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("名前")`, 2},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
@@ -572,8 +1466,20 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
+		{
+			"let a = [1, 2, 3]; a[-1] = 10; a[2]",
+			10,
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -586,6 +1492,141 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestIndexOutOfRange(t *testing.T) {
+	tests := []struct {
+		input           string
+		strict          bool
+		expectedMessage string
+	}{
+		// 越界写入总是返回错误
+		{"let a = [1, 2, 3]; a[3] = 1", false, "index out of range [3] with length 3"},
+		{"let a = [1, 2, 3]; a[-4] = 1", false, "index out of range [-4] with length 3"},
+		{"let a = []; a[0] = 1", false, "index out of range [0] with length 0"},
+		{"let h = {}; h[[1]] = 1", false, "unusable as hash key: ARRAY"},
+		{"let a = [1]; a[1] += 1", false, "index out of range [1] with length 1"},
+		{"let a = [1]; a[-2]++", false, "index out of range [-2] with length 1"},
+		{`let x = "abc"; x[0] = "z"`, false, "cannot assign to index of STRING"},
+		{`let x = "abc"; x[0] += "z"`, false, "cannot assign to index of STRING"},
+		{"[1, 2, 3][3]", true, "index out of range [3] with length 3"},
+		{"[1, 2, 3][-4]", true, "index out of range [-4] with length 3"},
+		{`"héllo"[5]`, true, "index out of range [5] with length 5"},
+	}
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.SetStrictIndexing(tt.strict)
+		evaluated := testEvalIn(tt.input, env)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+
+	// 默认关闭严格模式 越界读取返回 NULL 严格模式只对开启它的求值生效
+	strict := object.NewEnvironment()
+	strict.SetStrictIndexing(true)
+	if _, ok := testEvalIn("[1, 2, 3][3]", strict).(*object.Error); !ok {
+		t.Errorf("expected error for out-of-range read in strict environment")
+	}
+	testNullObject(t, testEvalIn("[1, 2, 3][3]", object.NewEnvironment()))
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc"[0]`, "a"},
+		{`"héllo"[1]`, "é"},
+		{`let 名前 = "世界"; 名前[1]`, "界"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, "c"},
+		{`"héllo"[-4]`, "é"},
+		{`"abc"[-4]`, nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-3]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][::2]", "[1, 3, 5]"},
+		{"[1, 2, 3, 4, 5][1::2]", "[2, 4]"},
+		{"[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]"},
+		{"[1, 2, 3, 4, 5][3:0:-1]", "[4, 3, 2]"},
+		{"[1, 2, 3, 4, 5][-1:-4:-2]", "[5, 3]"},
+		{"[1, 2, 3, 4, 5][10:20]", "[]"},
+		{"[1, 2, 3, 4, 5][-10:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:1]", "[]"},
+		{`"hello"[1:4]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"héllo wörld"[-5:]`, "wörld"},
+		{`"日本語"[1:]`, "本語"},
+		// 步长很大时不能溢出
+		{"[1, 2, 3][1::9223372036854775807]", "[2]"},
+		{`"abc"[2::9223372036854775807]`, "c"},
+		{"[1, 2, 3][1::-9223372036854775807]", "[2]"},
+		{"[1, 2, 3][::-9223372036854775807 - 1]", "[3]"},
+		{"[1, 2, 3][:2:9223372036854775807]", "[1]"},
+		// 切片总是返回新的数组
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 10; a", "[1, 2, 3]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"[1, 2][::0]", "slice step cannot be zero"},
+		{`"abc"[::0]`, "slice step cannot be zero"},
+		{`[1, 2]["a":]`, "slice start must be INTEGER, got STRING"},
+		{"[1, 2][:true]", "slice end must be INTEGER, got BOOLEAN"},
+		{"5[1:2]", "slice operator not supported: INTEGER"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -681,6 +1722,37 @@ func TestAssignExpressions(t *testing.T) {
 		{"let a = 5; let b = 1; a = b = 10; b;", 10},
 		{"let a = {}; a[1] = 10; a[1];", 10},
 		{"let a = [0,1,2]; a[1] = 10; a[1];", 10},
+		{"let a = 5; a += 3; a", 8},
+		{"let a = 5; a -= 3; a", 2},
+		{"let a = 5; a *= 3; a", 15},
+		{"let a = 7; a %= 3; a", 1},
+		{"let a = 5; let b = a += 1; a + b", 12},
+		{"let a = [1, 2, 3]; a[1] += 10; a[1]", 12},
+		{"let a = [1, 2, 3]; a[-1] *= 2; a[2]", 6},
+		{`let h = {"n": 1}; h["n"] += 1; h.n`, 2},
+		{`let h = {"n": 1}; h.n += 5; h.n`, 6},
+		{"let a = 5; a++; a", 6},
+		{"let a = 5; a--; a", 4},
+		{"let a = 5; a++", 5},
+		{"let a = [1, 2]; a[0]++; a[0]", 2},
+		{`let h = {"n": 1}; h.n--; h.n`, 0},
+		// 前缀自增自减的结果为修改之后的值
+		{"let x = 5; --x", 4},
+		{"let x = 5; --x; x", 4},
+		{"let x = 5; ++x", 6},
+		{"let x = 5; ++x + x", 12},
+		{"let a = [1, 2]; ++a[-1]; a[1]", 3},
+		{`let h = {"n": 1}; --h.n + h.n`, 0},
+		{"let i = 1; let xs = [--i, ++i]; xs[0] * 10 + xs[1]", 1},
+		// -- 和 ++ 一样总是自减 减去负数需要用空格分开
+		{"5 - -3", 8},
+		{"let x = 5; x--; 10 - -x", 14},
+		{"let x = 5; x-- - 1", 4},
+		{"let x = 5; x++ + 1", 6},
+		{"let i = 0; while (i < 10) { i++; }; i", 10},
+		// 目标中的子表达式只求值一次
+		{"let calls = 0; let a = [1, 2, 3]; let f = fn() { calls++; 1 }; a[f()] += 10; a[f()]++; calls * 100 + a[1]", 213},
+		{`let calls = 0; let h = {"n": 1}; let g = fn() { calls++; h }; g().n += 1; g().n++; calls * 100 + h.n`, 203},
 	}
 	for _, tt := range tests {
 		testIntegerObject(t, testEval(tt.input), tt.expected)
@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"strings"
+)
+
+// method 内置类型的方法，receiver 为调用方法的对象
+type method func(receiver object.Object, args ...object.Object) object.Object
+
+// methods 每种类型各自的方法表
+// 在 init 中初始化，因为 map、filter 等方法需要调用 applyFunction，直接初始化会产生初始化循环
+var methods map[object.ObjectType]map[string]method
+
+func init() {
+	methods = map[object.ObjectType]map[string]method{
+		object.STRING_OBJ: {
+			"len": builtinMethod("len"),
+			"upper": stringMethod("upper", 0, func(s string, args []string) object.Object {
+				return &object.String{Value: strings.ToUpper(s)}
+			}),
+			"lower": stringMethod("lower", 0, func(s string, args []string) object.Object {
+				return &object.String{Value: strings.ToLower(s)}
+			}),
+			"trim": stringMethod("trim", 0, func(s string, args []string) object.Object {
+				return &object.String{Value: strings.TrimSpace(s)}
+			}),
+			"contains": stringMethod("contains", 1, func(s string, args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.Contains(s, args[0]))
+			}),
+			"startsWith": stringMethod("startsWith", 1, func(s string, args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.HasPrefix(s, args[0]))
+			}),
+			"endsWith": stringMethod("endsWith", 1, func(s string, args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.HasSuffix(s, args[0]))
+			}),
+			"replace": stringMethod("replace", 2, func(s string, args []string) object.Object {
+				return &object.String{Value: strings.ReplaceAll(s, args[0], args[1])}
+			}),
+			"split": stringMethod("split", 1, func(s string, args []string) object.Object {
+				arr := object.Array{}
+				for _, part := range strings.Split(s, args[0]) {
+					arr = append(arr, &object.String{Value: part})
+				}
+				return arr
+			}),
+		},
+		object.ARRAY_OBJ: {
+			"len":    builtinMethod("len"),
+			"first":  builtinMethod("first"),
+			"last":   builtinMethod("last"),
+			"rest":   builtinMethod("rest"),
+			"push":   builtinMethod("push"),
+			"map":    arrayMap,
+			"filter": arrayFilter,
+			"reduce": arrayReduce,
+			"join":   arrayJoin,
+		},
+		object.HASH_OBJ: {
+			"keys":   hashKeys,
+			"values": hashValues,
+			"has":    hashHas,
+		},
+	}
+}
+
+// evalMemberExpression 对成员访问表达式求值
+// 哈希表优先查找同名的字符串键，其余情况在方法表中查找方法
+// 找到的方法会与 receiver 绑定为一个内置函数，因此 "abc".upper 可以像普通函数一样传递和调用
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	if hash, ok := obj.(*object.Hash); ok {
+		key := &object.String{Value: name}
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+	}
+	if m, ok := methods[obj.Type()][name]; ok {
+		return object.BuiltinFunction(func(args ...object.Object) object.Object {
+			return m(obj, args...)
+		})
+	}
+	if obj.Type() == object.HASH_OBJ {
+		return NULL
+	}
+	return newError("undefined method %s for %s", name, obj.Type())
+}
+
+// builtinMethod 将内置函数包装为方法，receiver 作为第一个参数传入
+func builtinMethod(name string) method {
+	return func(receiver object.Object, args ...object.Object) object.Object {
+		return builtins[name].Fn(append([]object.Object{receiver}, args...)...)
+	}
+}
+
+// stringMethod 创建参数全部为字符串的字符串方法
+func stringMethod(name string, n int, fn func(s string, args []string) object.Object) method {
+	return func(receiver object.Object, args ...object.Object) object.Object {
+		if len(args) != n {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+		}
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			str, ok := arg.(*object.String)
+			if !ok {
+				return newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+			}
+			strs[i] = str.Value
+		}
+		return fn(receiver.(*object.String).Value, strs)
+	}
+}
+
+// arrayMap 对每个元素调用函数 返回由结果组成的新数组
+func arrayMap(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arr := receiver.(object.Array)
+	result := make(object.Array, 0, len(arr))
+	for _, elem := range arr {
+		val := applyFunction(args[0], []object.Object{elem})
+		if isError(val) {
+			return val
+		}
+		result = append(result, val)
+	}
+	return result
+}
+
+// arrayFilter 返回由函数返回真值的元素组成的新数组
+func arrayFilter(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	result := object.Array{}
+	for _, elem := range receiver.(object.Array) {
+		val := applyFunction(args[0], []object.Object{elem})
+		if isError(val) {
+			return val
+		}
+		if isTruthy(val) {
+			result = append(result, elem)
+		}
+	}
+	return result
+}
+
+// arrayReduce 从初始值开始依次使用函数合并每个元素 reduce(fn(acc, x) { ... }, initial)
+func arrayReduce(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	acc := args[1]
+	for _, elem := range receiver.(object.Array) {
+		acc = applyFunction(args[0], []object.Object{acc, elem})
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// arrayJoin 使用分隔符连接所有元素的字符串表示
+func arrayJoin(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	sep, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `join` must be STRING, got %s", args[0].Type())
+	}
+	arr := receiver.(object.Array)
+	parts := make([]string, len(arr))
+	for i, elem := range arr {
+		parts[i] = elem.Inspect()
+	}
+	return &object.String{Value: strings.Join(parts, sep.Value)}
+}
+
+// hashKeys 按插入顺序返回所有键
+func hashKeys(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	hash := receiver.(*object.Hash)
+	keys := make(object.Array, 0, len(hash.Keys))
+	for _, key := range hash.Keys {
+		keys = append(keys, hash.Pairs[key].Key)
+	}
+	return keys
+}
+
+// hashValues 按插入顺序返回所有值
+func hashValues(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	hash := receiver.(*object.Hash)
+	values := make(object.Array, 0, len(hash.Keys))
+	for _, key := range hash.Keys {
+		values = append(values, hash.Pairs[key].Value)
+	}
+	return values
+}
+
+// hashHas 判断是否存在指定的键
+func hashHas(receiver object.Object, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	hashed, ok := args[0].(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", args[0].Type())
+	}
+	_, ok = receiver.(*object.Hash).Pairs[hashed.HashKey()]
+	return nativeBoolToBooleanObject(ok)
+}
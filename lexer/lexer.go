@@ -1,51 +1,216 @@
 package lexer
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
+	"io"
 	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// bufferSize 从 io.Reader 读取源码时使用的缓冲区大小
+const bufferSize = 4096
+
 type Lexer struct {
-	input        string
-	position     int  // 所输入字符串中的当前位置(指向当前字符)
-	readPosition int  // 所输入字符串中的当前读取位置(指向当前字符之后的一个字符)
-	ch           byte // 当前正在查看的字符
+	reader       *bufio.Reader
+	readErr      error  // 读取源码时遇到的错误，io.EOF 不会被记录
+	position     int    // 当前字符在源码中的字节偏移
+	readPosition int    // 当前字符之后的一个字符在源码中的字节偏移
+	ch           rune   // 当前正在查看的字符
+	raw          []byte // 当前字符在源码中的原始字节
+	literal      []byte // 当前词元从起始位置到当前字符之前的原始字节
+	filename     string // 源码文件名，仅用于记录位置信息
+	line         int    // 当前字符所在行，从 1 开始
+	column       int    // 当前字符所在列，从 1 开始
+	emitComments bool   // 是否将注释作为 COMMENT 词元返回
+	errors       []Error
+	errMsg       string // 最近一个 ILLEGAL 词元对应的错误信息
+
+	// templates 记录尚未结束的字符串插值 ${...}
+	// 每个元素为对应插值表达式内尚未闭合的 { 的数量，遇到数量为 0 时的 } 表示插值结束
+	templates []int
+}
+
+// Error 词法错误，每一个 ILLEGAL 词元都对应一个 Error
+type Error struct {
+	Pos token.Position
+	End token.Position
+	Msg string
+}
+
+func (e Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Option 用于在创建 Lexer 时进行配置
+type Option func(*Lexer)
+
+// WithFilename 设置源码文件名，文件名会被记录到每个词元的位置信息中
+func WithFilename(filename string) Option {
+	return func(l *Lexer) {
+		l.filename = filename
+	}
+}
+
+// WithComments 让 Lexer 将注释作为 COMMENT 词元返回而不是直接跳过，便于格式化工具保留注释
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.emitComments = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer {
+	return NewReader(strings.NewReader(input), opts...)
 }
 
-func New(input string) *Lexer {
-	l := &Lexer{input: input}
+// NewReader 创建一个从 r 中逐步读取源码的 Lexer, 产生的词元与 New 完全相同
+// 读取时只使用固定大小的缓冲区，不会一次性将全部源码读入内存
+func NewReader(r io.Reader, opts ...Option) *Lexer {
+	l := &Lexer{reader: bufio.NewReaderSize(r, bufferSize), line: 1}
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.readChar()
 	return l
 }
 
-// readChar 读取 input 中的下一个字符
+// readChar 读取下一个 UTF-8 字符，同时维护当前字符的行列号
+// 列号以字符为单位计算
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
 	}
+	l.literal = append(l.literal, l.raw...)
+	ch, raw := l.decode()
+	l.ch = ch
+	l.raw = append(l.raw[:0], raw...)
+	l.reader.Discard(len(raw))
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += len(l.raw)
+	l.column++
+}
+
+// decode 解码缓冲区中尚未读取的第一个字符，返回该字符以及它的原始字节，不会移动读取位置
+// 读取到源码末尾或者发生读取错误时返回 0
+// 只读取当前字符需要的字节，避免从流中读取时为了一个单字节字符等待后续的输入
+func (l *Lexer) decode() (rune, []byte) {
+	buf, err := l.reader.Peek(1)
+	if len(buf) == 0 {
+		l.recordReadErr(err)
+		return 0, nil
+	}
+	if buf[0] >= utf8.RuneSelf {
+		// 多字节字符根据首字节读取完整的编码，字节不足时 DecodeRune 返回 RuneError
+		buf, err = l.reader.Peek(runeLen(buf[0]))
+		l.recordReadErr(err)
+	}
+	ch, size := utf8.DecodeRune(buf)
+	return ch, buf[:size]
+}
+
+// recordReadErr 记录第一个读取错误，io.EOF 不是错误
+func (l *Lexer) recordReadErr(err error) {
+	if err != nil && err != io.EOF && l.readErr == nil {
+		l.readErr = err
+	}
+}
+
+// runeLen 根据 UTF-8 编码的首字节返回字符编码的字节数，无效的首字节按 1 个字节处理
+func runeLen(b byte) int {
+	switch {
+	case b&0xE0 == 0xC0:
+		return 2
+	case b&0xF0 == 0xE0:
+		return 3
+	case b&0xF8 == 0xF0:
+		return 4
+	}
+	return 1
 }
 
+// pos 返回当前字符的位置
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+// NextToken 返回下一个词元，并记录词元的起止位置
+// 未开启 WithComments 时注释会被跳过
 func (l *Lexer) NextToken() token.Token {
-	// 跳过所有空白字符
-	l.skipWhitespace()
+	for {
+		// 跳过所有空白字符
+		l.skipWhitespace()
+
+		start := l.pos()
+		l.literal = l.literal[:0]
+		tok := l.nextToken()
+		tok.Pos = start
+		tok.End = l.pos()
+		if tok.Type == token.ILLEGAL {
+			l.errors = append(l.errors, Error{Pos: tok.Pos, End: tok.End, Msg: l.errMsg})
+		}
+		if tok.Type == token.COMMENT && !l.emitComments {
+			continue
+		}
+		return tok
+	}
+}
+
+// Errors 返回目前为止遇到的所有词法错误
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
 
+// illegal 构造一个 ILLEGAL 词元，并记录对应的错误信息
+func (l *Lexer) illegal(literal string, format string, a ...interface{}) token.Token {
+	l.errMsg = fmt.Sprintf(format, a...)
+	return token.Token{Type: token.ILLEGAL, Literal: literal}
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
+		switch l.peekChar() {
+		case '=':
 			l.readChar()
 			tok = newToken(token.EQ, '=', '=')
-		} else {
+		case '>':
+			l.readChar()
+			tok = newToken(token.ARROW, '=', '>')
+		default:
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = newToken(token.PLUS_ASSIGN, '+', '=')
+		case '+':
+			l.readChar()
+			tok = newToken(token.INCREMENT, '+', '+')
+		default:
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = newToken(token.MINUS_ASSIGN, '-', '=')
+		case '-':
+			l.readChar()
+			tok = newToken(token.DECREMENT, '-', '-')
+		default:
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			l.readChar()
@@ -54,23 +219,75 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		switch l.peekChar() {
+		case '*':
+			l.readChar()
+			tok = newToken(token.POWER, '*', '*')
+		case '=':
+			l.readChar()
+			tok = newToken(token.ASTERISK_ASSIGN, '*', '=')
+		default:
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = newToken(token.PERCENT_ASSIGN, '%', '=')
+		} else {
+			tok = newToken(token.PERCENT, l.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			return l.readLineComment()
+		case '*':
+			return l.readBlockComment()
+		case '=':
+			l.readChar()
+			tok = newToken(token.SLASH_ASSIGN, '/', '=')
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '>':
-		if l.peekChar() == '=' {
+		switch l.peekChar() {
+		case '=':
 			l.readChar()
 			tok = newToken(token.GTE, '>', '=')
-		} else {
+		case '>':
+			l.readChar()
+			tok = newToken(token.SHR, '>', '>')
+		default:
 			tok = newToken(token.GT, l.ch)
 		}
 	case '<':
-		if l.peekChar() == '=' {
+		switch l.peekChar() {
+		case '=':
 			l.readChar()
 			tok = newToken(token.LTE, '<', '=')
-		} else {
+		case '<':
+			l.readChar()
+			tok = newToken(token.SHL, '<', '<')
+		default:
 			tok = newToken(token.LT, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = newToken(token.AND, '&', '&')
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = newToken(token.OR, '|', '|')
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
@@ -82,20 +299,52 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '{':
+		if n := len(l.templates); n > 0 {
+			l.templates[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		if n := len(l.templates); n > 0 {
+			if l.templates[n-1] == 0 {
+				// 插值表达式结束 继续读取字符串剩余的部分
+				l.templates = l.templates[:n-1]
+				return l.readString(true)
+			}
+			l.templates[n-1]--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		return l.readString(false)
+	case '`':
+		return l.readRawString()
 	case '.':
-		tok = newToken(token.DOT, l.ch)
+		if isDigit(l.peekChar()) {
+			// 省略整数部分的浮点数 .5
+			return l.readNumber()
+		}
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '.' {
+				l.readChar()
+				tok = newToken(token.ELLIPSIS, '.', '.', '.')
+			} else {
+				tok = newToken(token.DOTDOT, '.', '.')
+			}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case 0:
-		tok = newToken(token.EOF)
+		if err := l.readErr; err != nil {
+			// 读取错误只报告一次 之后返回 EOF
+			l.readErr = nil
+			return l.illegal("", "read error: %v", err)
+		}
+		// 不再向后读取 保证重复调用时 EOF 的位置不变
+		return newToken(token.EOF)
 	default:
 		if isLetter(l.ch) {
 			// 识别标识符和关键字
@@ -104,31 +353,40 @@ func (l *Lexer) NextToken() token.Token {
 			return tok                                // readIdentifier 中已经将 pos 移动到了下一位，这里必须提前返回
 		} else if isDigit(l.ch) {
 			// 识别数字字面量
-			tok.Literal = l.readNumber()
-			tok.Type = token.DetermineNumberType(tok.Literal)
-			return tok
+			return l.readNumber()
+		} else if l.ch == utf8.RuneError {
+			tok = l.illegal(string(l.raw), "illegal UTF-8 encoding")
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = l.illegal(string(l.ch), "illegal character %q", string(l.ch))
 		}
 	}
 	l.readChar()
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch ...byte) token.Token {
+func newToken(tokenType token.TokenType, ch ...rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// readIdentifier 读取标识符，标识符以字母或 _ 开头，之后还可以包含数字和组合字符
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isIdentifierPart(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.text()
+}
+
+// isLetter 判断是否是 Unicode 字母或者 _
+func isLetter(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+	}
+	return unicode.IsLetter(ch)
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isIdentifierPart 判断是否是只能出现在标识符中间的字符，包括数字和组合字符(例如天城文的元音符号)
+func isIdentifierPart(ch rune) bool {
+	return unicode.IsDigit(ch) || unicode.In(ch, unicode.Mn, unicode.Mc)
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -137,47 +395,304 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	onceDot := false
-	for isDigit(l.ch) || l.ch == '.' {
-		if l.ch == '.' {
-			if onceDot {
-				break
+// readNumber 读取数字字面量，数字之间可以使用 _ 分隔
+//
+//	123 1_000_000       十进制整数
+//	0xff 0b1010 0o17    十六进制、二进制、八进制整数
+//	1.5 .5 1e10 2.5E-3  浮点数
+func (l *Lexer) readNumber() token.Token {
+	var errMsg string
+	setErr := func(msg string) {
+		if errMsg == "" {
+			errMsg = msg
+		}
+	}
+
+	base, name := 10, "decimal"
+	if l.ch == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			base, name = 16, "hexadecimal"
+		case 'b', 'B':
+			base, name = 2, "binary"
+		case 'o', 'O':
+			base, name = 8, "octal"
+		}
+	}
+
+	if base != 10 {
+		l.readChar()
+		l.readChar()
+		if n, msg := l.readDigits(base); n == 0 {
+			setErr(name + " literal has no digits")
+		} else if msg != "" {
+			setErr(msg)
+		}
+	} else {
+		if l.ch != '.' {
+			_, msg := l.readDigits(10)
+			setErr(msg)
+		}
+		// 小数点后必须是数字 这样 1..10 和 1.foo 中的 . 不会被当作小数点
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			l.readChar()
+			_, msg := l.readDigits(10)
+			setErr(msg)
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if n, msg := l.readDigits(10); n == 0 {
+				setErr("exponent has no digits")
+			} else {
+				setErr(msg)
+			}
+		}
+	}
+
+	// 数字之后紧跟的字母和数字都视为字面量的一部分 例如 0b102 123abc
+	if isLetter(l.ch) || isDigit(l.ch) {
+		setErr(fmt.Sprintf("invalid digit %q in %s literal", l.ch, name))
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	literal := l.text()
+	if errMsg != "" {
+		return l.illegal(literal, "%s", errMsg)
+	}
+	return token.Token{Type: token.DetermineNumberType(literal), Literal: literal}
+}
+
+// readDigits 读取 base 进制的数字和数字之间的 _ 分隔符，返回读取到的数字个数
+// _ 的位置不合法时返回错误信息
+func (l *Lexer) readDigits(base int) (int, string) {
+	var n int
+	var msg string
+	underscore := false // 上一个字符是否是 _
+	for isDigitOf(l.ch, base) || l.ch == '_' {
+		if l.ch == '_' {
+			if n == 0 || underscore {
+				msg = "'_' must separate successive digits"
 			}
-			onceDot = true
+			underscore = true
+		} else {
+			underscore = false
+			n++
 		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	if underscore {
+		msg = "'_' must separate successive digits"
+	}
+	return n, msg
+}
+
+// isDigitOf 判断 ch 是否是 base 进制的数字
+func isDigitOf(ch rune, base int) bool {
+	switch base {
+	case 2:
+		return ch == '0' || ch == '1'
+	case 8:
+		return '0' <= ch && ch <= '7'
+	case 16:
+		return isHexDigit(ch)
+	}
+	return isDigit(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) readString() string {
-	var buf bytes.Buffer
+// readLineComment 读取 // 注释直到行尾，返回的词元不包含行尾的换行符
+func (l *Lexer) readLineComment() token.Token {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return token.Token{Type: token.COMMENT, Literal: l.text()}
+}
+
+// readBlockComment 读取 /* */ 注释，注释可以嵌套
+func (l *Lexer) readBlockComment() token.Token {
+	depth := 0
 	for {
+		switch {
+		case l.ch == 0:
+			return l.illegal(l.text(), "block comment not terminated")
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
 		l.readChar()
-		// 支持转义符
-		if l.ch == '\\' && l.peekChar() == '"' {
-			buf.WriteByte('"')
+		if depth == 0 {
+			return token.Token{Type: token.COMMENT, Literal: l.text()}
+		}
+	}
+}
+
+// readString 读取双引号包裹的字符串并处理其中的转义序列
+// 调用时 l.ch 为起始的 " 或者插值结束的 }, 返回时 l.ch 为结束的 " 或者 ${ 之后的字符
+// 字符串不能跨行, 遇到换行或者文件结尾时返回 ILLEGAL
+//
+// 含有插值的字符串 "a ${x} b ${y} c" 会被切分为多个词元:
+// TEMPLATE_HEAD("a ") x TEMPLATE_MIDDLE(" b ") y TEMPLATE_TAIL(" c")
+// continued 表示当前读取的是插值之后的部分
+func (l *Lexer) readString(continued bool) token.Token {
+	var buf bytes.Buffer
+	var errMsg string // 只记录第一个非法的转义序列
+	l.readChar()
+	for {
+		switch l.ch {
+		case '"':
 			l.readChar()
+			if errMsg != "" {
+				return l.illegal(l.text(), "%s", errMsg)
+			}
+			typ := token.STRING
+			if continued {
+				typ = token.TEMPLATE_TAIL
+			}
+			return token.Token{Type: typ, Literal: buf.String()}
+		case '$':
+			if l.peekChar() != '{' {
+				break
+			}
+			l.readChar()
+			l.readChar()
+			// 即使前面的片段有错误也要记录插值 保证后续的 } 能被正确识别
+			l.templates = append(l.templates, 0)
+			if errMsg != "" {
+				return l.illegal(l.text(), "%s", errMsg)
+			}
+			typ := token.TEMPLATE_HEAD
+			if continued {
+				typ = token.TEMPLATE_MIDDLE
+			}
+			return token.Token{Type: typ, Literal: buf.String()}
+		case 0, '\n':
+			return l.illegal(l.text(), "string literal not terminated")
+		case '\\':
+			if msg := l.readEscape(&buf); msg != "" && errMsg == "" {
+				errMsg = msg
+			}
 			continue
 		}
-		// 遇到反引号结束
-		if l.ch == '"' || l.ch == 0 {
-			break
+		buf.WriteRune(l.ch)
+		l.readChar()
+	}
+}
+
+// readEscape 读取一个转义序列并将其表示的字符写入 buf, 转义序列非法时返回错误信息
+// 调用时 l.ch 为 \, 返回时 l.ch 为转义序列之后的字符
+//
+//	\n \t \r \0 \\ \" \' \$  常用转义字符
+//	\xHH         两位十六进制表示的字符
+//	\u{H...}     1 到 6 位十六进制表示的 Unicode 码点
+func (l *Lexer) readEscape(buf *bytes.Buffer) string {
+	l.readChar()
+	switch l.ch {
+	case 0, '\n':
+		// 交给 readString 报告字符串未结束
+		return ""
+	case 'n':
+		buf.WriteByte('\n')
+	case 't':
+		buf.WriteByte('\t')
+	case 'r':
+		buf.WriteByte('\r')
+	case '0':
+		buf.WriteByte(0)
+	case '\\', '"', '\'', '$':
+		buf.WriteRune(l.ch)
+	case 'x':
+		var value rune
+		for i := 0; i < 2; i++ {
+			l.readChar()
+			if !isHexDigit(l.ch) {
+				return "invalid hex escape sequence, expected 2 hex digits"
+			}
+			value = value*16 + hexValue(l.ch)
+		}
+		buf.WriteRune(value)
+	case 'u':
+		l.readChar()
+		if l.ch != '{' {
+			return "invalid unicode escape sequence, expected \\u{...}"
+		}
+		l.readChar()
+		var value rune
+		digits := 0
+		for isHexDigit(l.ch) {
+			value = value*16 + hexValue(l.ch)
+			digits++
+			if digits > 6 {
+				return "invalid unicode escape sequence, too many hex digits"
+			}
+			l.readChar()
 		}
-		buf.WriteByte(l.ch)
+		if l.ch != '}' || digits == 0 {
+			return "invalid unicode escape sequence, expected \\u{...}"
+		}
+		if !utf8.ValidRune(value) {
+			return fmt.Sprintf("invalid unicode code point U+%X", value)
+		}
+		buf.WriteRune(value)
+	default:
+		msg := fmt.Sprintf("unknown escape sequence \\%c", l.ch)
+		l.readChar()
+		return msg
 	}
-	return buf.String()
+	l.readChar()
+	return ""
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
+// readRawString 读取反引号包裹的原始字符串, 原始字符串不处理转义并且可以跨越多行
+// 调用时 l.ch 为起始的 `, 返回时 l.ch 为结束的 ` 之后的字符
+func (l *Lexer) readRawString() token.Token {
+	var buf bytes.Buffer
+	l.readChar()
+	for l.ch != '`' {
+		if l.ch == 0 {
+			return l.illegal(l.text(), "raw string literal not terminated")
+		}
+		// 忽略 \r 保证不同平台下换行符一致
+		if l.ch != '\r' {
+			buf.WriteRune(l.ch)
+		}
+		l.readChar()
 	}
-	return l.input[l.readPosition]
+	l.readChar()
+	return token.Token{Type: token.STRING, Literal: buf.String()}
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
+func (l *Lexer) peekChar() rune {
+	ch, _ := l.decode()
+	return ch
+}
+
+// text 返回当前词元从起始位置到当前字符之前的源码
+func (l *Lexer) text() string {
+	return string(l.literal)
 }
@@ -1,8 +1,13 @@
 package lexer
 
 import (
+	"errors"
+	"io"
 	"monkey/token"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestNextToken(t *testing.T) {
@@ -14,7 +19,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -33,6 +38,15 @@ if (5 < 10) {
 3.1415926
 a >= b
 a <= b
+a && b || c
+7 div 2 % 3 ** 2
+~a & b | c ^ d << 1 >> 2
+for (k, v in 0..10) {}
+a += 1; a -= 1; a *= 1; a /= 1; a %= 1; a++; a--;
+--x; ++x; x--y; x++y; 5 - -3
+[...rest]
+match (x) { _ => 1 }
+try catch finally throw
 `
 
 	tests := []struct {
@@ -133,6 +147,103 @@ a <= b
 		{token.IDENT, "a"},
 		{token.LTE, "<="},
 		{token.IDENT, "b"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.INT, "7"},
+		{token.DIV, "div"},
+		{token.INT, "2"},
+		{token.PERCENT, "%"},
+		{token.INT, "3"},
+		{token.POWER, "**"},
+		{token.INT, "2"},
+		{token.TILDE, "~"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.IDENT, "d"},
+		{token.SHL, "<<"},
+		{token.INT, "1"},
+		{token.SHR, ">>"},
+		{token.INT, "2"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "k"},
+		{token.COMMA, ","},
+		{token.IDENT, "v"},
+		{token.IN, "in"},
+		{token.INT, "0"},
+		{token.DOTDOT, ".."},
+		{token.INT, "10"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.INCREMENT, "++"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.DECREMENT, "--"},
+		{token.SEMICOLON, ";"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.INCREMENT, "++"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.INCREMENT, "++"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "5"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INT, "3"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
 		{token.EOF, ""},
 	}
 
@@ -152,3 +263,424 @@ a <= b
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x >= 10
+"ab"`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "main.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "main.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "main.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "main.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "main.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "main.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "main.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "main.mk", Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Filename: "main.mk", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "main.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, token.Position{Filename: "main.mk", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "main.mk", Offset: 14, Line: 2, Column: 4}},
+		{token.GTE, token.Position{Filename: "main.mk", Offset: 15, Line: 2, Column: 5}, token.Position{Filename: "main.mk", Offset: 17, Line: 2, Column: 7}},
+		{token.INT, token.Position{Filename: "main.mk", Offset: 18, Line: 2, Column: 8}, token.Position{Filename: "main.mk", Offset: 20, Line: 2, Column: 10}},
+		{token.STRING, token.Position{Filename: "main.mk", Offset: 21, Line: 3, Column: 1}, token.Position{Filename: "main.mk", Offset: 25, Line: 3, Column: 5}},
+		{token.EOF, token.Position{Filename: "main.mk", Offset: 25, Line: 3, Column: 5}, token.Position{Filename: "main.mk", Offset: 25, Line: 3, Column: 5}},
+	}
+
+	l := New(input, WithFilename("main.mk"))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// line comment
+let a = 1; // trailing
+/* block /* nested */ still comment */ a / 2
+/* not terminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// line comment"},
+		{token.LET, "let"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block /* nested */ still comment */"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.ILLEGAL, "/* not terminated"},
+		{token.EOF, ""},
+	}
+
+	// 开启 WithComments 时注释作为词元返回
+	l := New(input, WithComments())
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// 默认跳过注释
+	l = New(input)
+	for i, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%d", len(errors))
+	}
+	if errors[0].Error() != "4:1: block comment not terminated" {
+		t.Errorf("error wrong. got=%q", errors[0].Error())
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedError   string
+	}{
+		{`"a\nb\tc\r"`, token.STRING, "a\nb\tc\r", ""},
+		{`"\\ \" \' \0"`, token.STRING, "\\ \" ' \x00", ""},
+		{`"\x41\x7a"`, token.STRING, "Az", ""},
+		{`"\u{4e16}\u{754C}\u{1F600}"`, token.STRING, "世界😀", ""},
+		{"`raw \\n \"string\"`", token.STRING, `raw \n "string"`, ""},
+		{"`line one\r\nline two`", token.STRING, "line one\nline two", ""},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape"`, "unknown escape sequence \\q"},
+		{`"\x4"`, token.ILLEGAL, `"\x4"`, "invalid hex escape sequence, expected 2 hex digits"},
+		{`"\u41"`, token.ILLEGAL, `"\u41"`, "invalid unicode escape sequence, expected \\u{...}"},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`, "invalid unicode code point U+110000"},
+		{`"unterminated`, token.ILLEGAL, `"unterminated`, "string literal not terminated"},
+		{"\"broken\nline\"", token.ILLEGAL, `"broken`, "string literal not terminated"},
+		{"`unterminated", token.ILLEGAL, "`unterminated", "raw string literal not terminated"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tt.expectedError == "" {
+			if len(l.Errors()) != 0 {
+				t.Fatalf("tests[%d] - unexpected errors: %v", i, l.Errors())
+			}
+			continue
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0].Msg != tt.expectedError {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%v",
+				i, tt.expectedError, l.Errors())
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"hello ${name}, ${ {"a": "${x}"}["a"] }!" "\${raw} $5"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE_HEAD, "hello "},
+		{token.IDENT, "name"},
+		{token.TEMPLATE_MIDDLE, ", "},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.TEMPLATE_HEAD, ""},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_TAIL, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.TEMPLATE_TAIL, "!"},
+		{token.STRING, "${raw} $5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let 名前 = \"héllo, 世界\";\nnaïve_2 + नाम; ∑ \xff"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{token.LET, "let", "1:1"},
+		{token.IDENT, "名前", "1:5"},
+		{token.ASSIGN, "=", "1:8"},
+		{token.STRING, "héllo, 世界", "1:10"},
+		{token.SEMICOLON, ";", "1:21"},
+		{token.IDENT, "naïve_2", "2:1"},
+		{token.PLUS, "+", "2:9"},
+		{token.IDENT, "नाम", "2:11"},
+		{token.SEMICOLON, ";", "2:14"},
+		{token.ILLEGAL, "∑", "2:16"},
+		{token.ILLEGAL, "\xff", "2:18"},
+		{token.EOF, "", "2:19"},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.String() != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%s, got=%s",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors. got=%d", len(errors))
+	}
+	if errors[0].Msg != `illegal character "∑"` {
+		t.Errorf("errors[0] wrong. got=%q", errors[0].Msg)
+	}
+	if errors[1].Msg != "illegal UTF-8 encoding" {
+		t.Errorf("errors[1] wrong. got=%q", errors[1].Msg)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedError   string
+	}{
+		{"123", token.INT, "123", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"0xff", token.INT, "0xff", ""},
+		{"0XFF_FF", token.INT, "0XFF_FF", ""},
+		{"0b1010", token.INT, "0b1010", ""},
+		{"0o17", token.INT, "0o17", ""},
+		{"1.5", token.FLOAT, "1.5", ""},
+		{".5", token.FLOAT, ".5", ""},
+		{"1e10", token.FLOAT, "1e10", ""},
+		{"2.5E-3", token.FLOAT, "2.5E-3", ""},
+		{"1_000.000_1", token.FLOAT, "1_000.000_1", ""},
+		{"0x1e", token.INT, "0x1e", ""},
+		{"0x", token.ILLEGAL, "0x", "hexadecimal literal has no digits"},
+		{"0b102", token.ILLEGAL, "0b102", "invalid digit '2' in binary literal"},
+		{"0o8", token.ILLEGAL, "0o8", "octal literal has no digits"},
+		{"123abc", token.ILLEGAL, "123abc", "invalid digit 'a' in decimal literal"},
+		{"1__0", token.ILLEGAL, "1__0", "'_' must separate successive digits"},
+		{"10_", token.ILLEGAL, "10_", "'_' must separate successive digits"},
+		{"1e", token.ILLEGAL, "1e", "exponent has no digits"},
+		{"1e+", token.ILLEGAL, "1e+", "exponent has no digits"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tt.expectedError != "" && (len(l.Errors()) != 1 || l.Errors()[0].Msg != tt.expectedError) {
+			t.Fatalf("tests[%d] - error wrong. expected=%q, got=%v",
+				i, tt.expectedError, l.Errors())
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after number. got=%q", i, next.Type)
+		}
+	}
+
+	// 小数点后不是数字时 . 不属于数字字面量
+	l := New("1.foo")
+	for _, expected := range []token.TokenType{token.INT, token.DOT, token.IDENT, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	// 长度超过缓冲区的字符串和注释，多字节字符会落在缓冲区的边界上
+	long := strings.Repeat("αβγ", bufferSize)
+	input := `let 名字 = "` + long + `";
+/* ` + long + ` */
+let add = fn(x, y) { x + y };
+let s = "a ${add(0x10, .5)} b";
+` + "`raw\nstring`" + ` 1_000 @ "\xff\u{1F600}"
+if (x >= 10) { return [1, 2]; } else { {"a": 1} }
+`
+
+	expected := lexAll(New(input, WithComments()))
+	readers := map[string]*Lexer{
+		"reader":      NewReader(strings.NewReader(input), WithComments()),
+		"one byte":    NewReader(iotest.OneByteReader(strings.NewReader(input)), WithComments()),
+		"half reader": NewReader(iotest.HalfReader(strings.NewReader(input)), WithComments()),
+	}
+
+	for name, l := range readers {
+		tokens := lexAll(l)
+		if len(tokens) != len(expected) {
+			t.Fatalf("%s - wrong number of tokens. expected=%d, got=%d", name, len(expected), len(tokens))
+		}
+		for i, tok := range tokens {
+			if tok != expected[i] {
+				t.Fatalf("%s - tokens[%d] wrong. expected=%+v, got=%+v", name, i, expected[i], tok)
+			}
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0].Msg != `illegal character "@"` {
+			t.Fatalf("%s - wrong errors. got=%v", name, l.Errors())
+		}
+	}
+
+	// 非法的 UTF-8 字节同样逐字节报告
+	l := NewReader(strings.NewReader("a\xffb"))
+	for _, expected := range []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.ILLEGAL, Literal: "\xff"},
+		{Type: token.IDENT, Literal: "b"},
+	} {
+		tok := l.NextToken()
+		if tok.Type != expected.Type || tok.Literal != expected.Literal {
+			t.Fatalf("token wrong. expected=%+v, got=%+v", expected, tok)
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("let x = 1;"), iotest.ErrReader(errors.New("connection reset")))
+	tokens := lexAll(NewReader(r))
+	types := []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON, token.ILLEGAL, token.EOF}
+	if len(tokens) != len(types) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(types), len(tokens))
+	}
+	for i, tok := range tokens {
+		if tok.Type != types[i] {
+			t.Fatalf("tokens[%d] - tokentype wrong. expected=%q, got=%q", i, types[i], tok.Type)
+		}
+	}
+
+	l := NewReader(iotest.ErrReader(errors.New("connection reset")))
+	l.NextToken()
+	if errs := l.Errors(); len(errs) != 1 || errs[0].Msg != "read error: connection reset" {
+		t.Fatalf("wrong errors. got=%v", errs)
+	}
+
+	// 多字节字符读取到一半时出错
+	r = io.MultiReader(strings.NewReader("x \xe4\xb8"), iotest.ErrReader(errors.New("connection reset")))
+	l = NewReader(r)
+	lexAll(l)
+	if errs := l.Errors(); len(errs) == 0 || errs[len(errs)-1].Msg != "read error: connection reset" {
+		t.Fatalf("wrong errors. got=%v", errs)
+	}
+}
+
+func TestNewReaderStreaming(t *testing.T) {
+	// 已经收到的字符足够组成词元时 不应该等待后续的输入
+	r, w := io.Pipe()
+	defer w.Close()
+	tokens := make(chan token.Token, 16)
+	go func() {
+		l := NewReader(r) // 创建时就会读取第一个字符
+		for {
+			tok := l.NextToken()
+			tokens <- tok
+			if tok.Type == token.EOF {
+				return
+			}
+		}
+	}()
+
+	for _, tt := range []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"let x ", []token.Token{{Type: token.LET, Literal: "let"}, {Type: token.IDENT, Literal: "x"}}},
+		{"= 名字 ", []token.Token{{Type: token.ASSIGN, Literal: "="}, {Type: token.IDENT, Literal: "名字"}}},
+	} {
+		go w.Write([]byte(tt.input))
+		for _, expected := range tt.expected {
+			select {
+			case tok := <-tokens:
+				if tok.Type != expected.Type || tok.Literal != expected.Literal {
+					t.Fatalf("token wrong. expected=%+v, got=%+v", expected, tok)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("lexer blocked waiting for input after %q", tt.input)
+			}
+		}
+	}
+}
+
+// lexAll 读取所有词元，包括最后的 EOF
+func lexAll(l *Lexer) []token.Token {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
@@ -1,10 +1,57 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/repl"
 	"os"
 )
 
 func main() {
-	repl.Start(os.Stdin, os.Stdout)
+	strict := flag.Bool("strict", false, "report out-of-range index reads as errors instead of null (reads return null by default)")
+	maxDepth := flag.Int("max-depth", object.DefaultMaxCallDepth, "maximum function call depth, 0 for no limit")
+	flag.Parse()
+
+	// 求值选项记录在最外层作用域中
+	env := object.NewEnvironment()
+	env.SetStrictIndexing(*strict)
+	env.SetMaxCallDepth(*maxDepth)
+
+	if flag.NArg() > 0 {
+		os.Exit(run(flag.Arg(0), os.Stdout, env))
+	}
+	repl.Start(os.Stdin, os.Stdout, env)
+}
+
+// run 在 env 中执行源码文件，源码通过 lexer.NewReader 逐步读取，返回进程的退出码
+func run(filename string, out io.Writer, env *object.Environment) int {
+	f, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	defer f.Close()
+
+	p := parser.New(lexer.NewReader(f, lexer.WithFilename(filename)))
+	prog := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		// 只有出错时才读取完整的源码用于展示出错的行
+		source, _ := os.ReadFile(filename)
+		for _, d := range p.Diagnostics() {
+			io.WriteString(out, d.Render(string(source)))
+		}
+		return 1
+	}
+
+	evaluated := evaluator.Eval(prog, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(out, err.Traceback())
+		return 1
+	}
+	return 0
 }
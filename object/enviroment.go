@@ -1,20 +1,69 @@
 package object
 
+// DefaultMaxCallDepth 新建的最外层作用域允许的函数调用最大嵌套深度
+const DefaultMaxCallDepth = 10000
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	env := &Environment{store: s, maxCallDepth: DefaultMaxCallDepth}
+	env.root = env
+	return env
 }
 
 func NewEnclosedEnviroment(outer *Environment) *Environment {
 	return &Environment{
 		store: make(map[string]Object),
 		outer: outer,
+		root:  outer.root,
 	}
 }
 
 type Environment struct {
-	store map[string]Object // 当前作用域内的值
-	outer *Environment      // 指向上层作用域
+	store     map[string]Object // 当前作用域内的值
+	outer     *Environment      // 指向上层作用域
+	root      *Environment      // 最外层作用域
+	callDepth int               // 函数调用的嵌套深度 只记录在最外层作用域中
+
+	// 以下求值选项只记录在最外层作用域中 使用不同最外层作用域的求值互不影响
+	maxCallDepth   int  // 函数调用的最大嵌套深度 小于等于 0 时不限制
+	strictIndexing bool // 读取数组和字符串越界的下标时是否返回错误
+}
+
+// CallDepth 返回函数调用的嵌套深度
+// 深度记录在最外层作用域中 因此使用不同的最外层作用域求值时互不影响
+func (e *Environment) CallDepth() int {
+	return e.root.callDepth
+}
+
+// EnterCall 进入一次函数调用 调用结束时需要调用 ExitCall
+func (e *Environment) EnterCall() {
+	e.root.callDepth++
+}
+
+// ExitCall 结束一次函数调用
+func (e *Environment) ExitCall() {
+	e.root.callDepth--
+}
+
+// MaxCallDepth 返回函数调用的最大嵌套深度 默认为 DefaultMaxCallDepth 小于等于 0 时不限制
+func (e *Environment) MaxCallDepth() int {
+	return e.root.maxCallDepth
+}
+
+// SetMaxCallDepth 设置函数调用的最大嵌套深度 对共享同一个最外层作用域的所有作用域生效
+func (e *Environment) SetMaxCallDepth(depth int) {
+	e.root.maxCallDepth = depth
+}
+
+// StrictIndexing 返回读取数组和字符串越界的下标时是否返回错误
+// 默认关闭 此时越界读取的结果为 NULL 越界写入总是返回错误
+func (e *Environment) StrictIndexing() bool {
+	return e.root.strictIndexing
+}
+
+// SetStrictIndexing 设置读取数组和字符串越界的下标时是否返回错误
+func (e *Environment) SetStrictIndexing(strict bool) {
+	e.root.strictIndexing = strict
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -48,7 +97,7 @@ func (e *Environment) Set(name string, val Object) Object {
 func (e *Environment) Assign(name string, val Object) Object {
 	_, env, ok := e.getWithEnv(name)
 	if !ok {
-		return &Error{Message: "illegal assign, symbol not exist: " + name}
+		return &Error{Message: "illegal assign, symbol not exist: " + name, Kind: RUNTIME_ERROR}
 	}
 	env.Set(name, val)
 	return val
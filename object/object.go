@@ -7,7 +7,9 @@ import (
 	"hash/fnv"
 	"math"
 	"monkey/ast"
+	"monkey/token"
 	"strings"
+	"unicode/utf8"
 )
 
 type ObjectType string
@@ -19,11 +21,15 @@ const (
 	STRING_OBJ       = "STRING"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	BULTIN_OBJ       = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
 )
 
 // Object 用来表示解释器中的值
@@ -128,6 +134,36 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// Len 返回字符串的字符数，字符串的长度、索引和切片都以字符(rune)而不是字节为单位
+func (s *String) Len() int {
+	return utf8.RuneCountInString(s.Value)
+}
+
+// Index 返回第 i 个字符组成的字符串，i 越界时返回 false
+func (s *String) Index(i int) (*String, bool) {
+	if i < 0 {
+		return nil, false
+	}
+	for _, ch := range s.Value {
+		if i == 0 {
+			return &String{Value: string(ch)}, true
+		}
+		i--
+	}
+	return nil, false
+}
+
+// Slice 从第 start 个字符开始每隔 step 个字符取一个，直到第 end 个字符(不包含 end)，组成新的字符串
+// 下标和步长需要由调用方规范化到合法范围，step 为负数时反向截取，step 为 0 时结果为空字符串
+func (s *String) Slice(start, end, step int) *String {
+	runes := []rune(s.Value)
+	out := make([]rune, 0)
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		out = append(out, runes[i])
+	}
+	return &String{Value: string(out)}
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType {
@@ -151,9 +187,63 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
-// Error 表示求值错误
+// TailCall 标识函数体中处于尾部位置的函数调用
+// 与 ReturnValue 一样向上传递 直到 applyFunction 在当前的 Go 栈帧中执行这次调用
+type TailCall struct {
+	Function  *Function
+	Arguments []Object
+	Pos       token.Position // 调用的位置
+}
+
+func (tc *TailCall) Type() ObjectType {
+	return TAIL_CALL_OBJ
+}
+
+func (tc *TailCall) Inspect() string {
+	return "tail call"
+}
+
+// Break 标识循环中遇到了 break 语句
+// 与 ReturnValue 一样会穿过嵌套的语句块向上传递 直到遇到循环
+type Break struct{}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+// Continue 标识循环中遇到了 continue 语句
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// 错误的类别
+const (
+	RUNTIME_ERROR = "RuntimeError" // 求值过程中解释器产生的错误
+	THROWN_ERROR  = "Error"        // throw 抛出的错误没有指定类别时的默认类别
+)
+
+// Error 表示求值错误 可以被 try/catch 捕获
 type Error struct {
 	Message string
+	Kind    string         // 错误的类别
+	Pos     token.Position // 最先感知到错误的节点的位置
+	Stack   []Frame        // 错误传递时经过的函数调用 最内层的调用在最前面
+}
+
+// Frame 调用栈中的一帧 Function 为被调用的函数名 Pos 为调用该函数的位置
+type Frame struct {
+	Function string
+	Pos      token.Position
 }
 
 func (e *Error) Type() ObjectType {
@@ -164,9 +254,52 @@ func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
+// Traceback 返回错误信息以及调用栈 每一行为一个函数以及函数中出错或者发起调用的位置
+// 递归调用产生的连续相同的行只输出一次
+//
+//	ERROR: identifier not found: x
+//	    at inner (2:3)
+//	    at outer (5:3)
+//	    at <main> (7:1)
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+	out.WriteString(e.Inspect())
+	var prev string
+	repeated := 0
+	flush := func() {
+		if repeated > 0 {
+			fmt.Fprintf(&out, "\n    [previous line repeated %d more times]", repeated)
+			repeated = 0
+		}
+	}
+	pos := e.Pos
+	for i := 0; i <= len(e.Stack); i++ {
+		line := "<main>"
+		if i < len(e.Stack) {
+			line = e.Stack[i].Function
+		}
+		if pos.IsValid() {
+			line += " (" + pos.String() + ")"
+		}
+		if i < len(e.Stack) {
+			pos = e.Stack[i].Pos
+		}
+		if line == prev {
+			repeated++
+			continue
+		}
+		flush()
+		out.WriteString("\n    at " + line)
+		prev = line
+	}
+	flush()
+	return out.String()
+}
+
 // Function 函数的值表示 一等公民
 type Function struct {
-	Parameters []*ast.Identifier   //继承自 AST 节点
+	Name       string              // 函数申明语句中的函数名 匿名函数为空
+	Parameters []ast.Pattern       //继承自 AST 节点
 	Body       *ast.BlockStatement // 继承自 AST 节点
 	Env        *Environment        // 函数内部变量 可以实现闭包
 }
@@ -200,6 +333,21 @@ func (bf BuiltinFunction) Inspect() string {
 	return "builtin function"
 }
 
+// Builtin 声明了形参名的内置函数 可以使用关键字参数调用 push(array: xs, elem: 1)
+// Params 为 nil 时只接受位置参数
+type Builtin struct {
+	Params []string
+	Fn     BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType {
+	return BULTIN_OBJ
+}
+
+func (b *Builtin) Inspect() string {
+	return "builtin function"
+}
+
 type Array []Object
 
 func (a Array) Type() ObjectType {
@@ -225,6 +373,19 @@ type HashPair struct {
 
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey // 键的插入顺序，遍历哈希表时按照这个顺序
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]HashPair{}}
+}
+
+// Set 设置键值对，新的键会被追加到插入顺序的末尾，已经存在的键保持原来的位置
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -232,7 +393,8 @@ func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -241,3 +403,15 @@ func (h *Hash) Inspect() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// Range 表示左闭右开的整数区间 start..end
+type Range struct {
+	Start int64
+	End   int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
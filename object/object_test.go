@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"monkey/token"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +20,92 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestStringRunes(t *testing.T) {
+	s := &String{Value: "héllo, 世界"}
+	if s.Len() != 9 {
+		t.Errorf("s.Len() wrong. want=9, got=%d", s.Len())
+	}
+
+	indexTests := []struct {
+		index    int
+		expected string
+		ok       bool
+	}{
+		{0, "h", true},
+		{1, "é", true},
+		{7, "世", true},
+		{8, "界", true},
+		{9, "", false},
+		{-1, "", false},
+	}
+	for _, tt := range indexTests {
+		ch, ok := s.Index(tt.index)
+		if ok != tt.ok {
+			t.Errorf("s.Index(%d) ok wrong. want=%t, got=%t", tt.index, tt.ok, ok)
+			continue
+		}
+		if ok && ch.Value != tt.expected {
+			t.Errorf("s.Index(%d) wrong. want=%q, got=%q", tt.index, tt.expected, ch.Value)
+		}
+	}
+
+	// 下标和步长已经规范化 与求值器中 sliceBounds 的结果相同
+	sliceTests := []struct {
+		start, end, step int
+		expected         string
+	}{
+		{0, 5, 1, "héllo"},
+		{7, 9, 1, "世界"},
+		{5, 3, 1, ""},
+		{0, 9, 2, "hlo 界"},
+		{8, -1, -1, "界世 ,olléh"},
+		{8, 6, -1, "界世"},
+		{1, 9, 10, "é"},
+		{8, -1, -10, "界"},
+		{0, 9, 0, ""},
+	}
+	for _, tt := range sliceTests {
+		if got := s.Slice(tt.start, tt.end, tt.step).Value; got != tt.expected {
+			t.Errorf("s.Slice(%d, %d, %d) wrong. want=%q, got=%q", tt.start, tt.end, tt.step, tt.expected, got)
+		}
+	}
+}
+
+func TestErrorTraceback(t *testing.T) {
+	err := &Error{
+		Message: "identifier not found: x",
+		Pos:     token.Position{Filename: "a.mk", Line: 2, Column: 3},
+		Stack: []Frame{
+			{Function: "inner", Pos: token.Position{Filename: "a.mk", Line: 5, Column: 3}},
+			{Function: "<anonymous>"},
+		},
+	}
+	expected := "ERROR: identifier not found: x\n" +
+		"    at inner (a.mk:2:3)\n" +
+		"    at <anonymous> (a.mk:5:3)\n" +
+		"    at <main>"
+	if got := err.Traceback(); got != expected {
+		t.Errorf("wrong traceback. expected=%q, got=%q", expected, got)
+	}
+
+	// 递归调用产生的连续相同的行只输出一次
+	pos := token.Position{Line: 1, Column: 15}
+	err = &Error{
+		Message: "maximum recursion depth 3 exceeded",
+		Pos:     pos,
+		Stack:   []Frame{{"f", pos}, {"f", pos}, {"f", token.Position{Line: 2, Column: 1}}},
+	}
+	expected = "ERROR: maximum recursion depth 3 exceeded\n" +
+		"    at f (1:15)\n" +
+		"    [previous line repeated 2 more times]\n" +
+		"    at <main> (2:1)"
+	if got := err.Traceback(); got != expected {
+		t.Errorf("wrong traceback. expected=%q, got=%q", expected, got)
+	}
+
+	err = &Error{Message: "boom"}
+	if got := err.Traceback(); got != "ERROR: boom\n    at <main>" {
+		t.Errorf("wrong traceback. got=%q", got)
+	}
+}
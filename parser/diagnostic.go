@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity 诊断信息的严重程度
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// 诊断代码，每一类语法错误对应一个固定的代码，便于工具按类别处理
+const (
+	CodeUnexpectedToken   = "E0001" // 下一个词元不是期望的词元
+	CodeMissingExpression = "E0002" // 需要表达式的位置没有可以解析的表达式
+	CodeInvalidNumber     = "E0003" // 数字字面量无法解析
+	CodeIllegalToken      = "E0004" // 词法错误，例如无法识别的字符、未闭合的注释
+	CodeNumberOverflow    = "E0005" // 数字字面量超出了可以表示的范围
+)
+
+// Diagnostic 语法分析过程中产生的一条诊断信息
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Pos      token.Position    // 出错的起始位置
+	End      token.Position    // 出错的结束位置(不包含)
+	Expected []token.TokenType // 在出错位置期望出现的词元，可能为空
+}
+
+// String 返回单行的诊断描述，例如 1:9: error[E0001]: expected next token to be ), got ; instead
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Pos, d.Severity, d.Code, d.Message)
+}
+
+func (d *Diagnostic) Error() string {
+	return d.String()
+}
+
+// Render 将诊断信息渲染为多行文本，在出错的源码行下方用 ^ 标出出错的范围
+// source 为完整的源码，位置信息中的偏移量基于它计算
+//
+//	1:15: error[E0001]: expected next token to be ), got ; instead
+//	  1 | let x = (1 + 2;
+//	    |               ^
+func (d *Diagnostic) Render(source string) string {
+	var out strings.Builder
+	out.WriteString(d.String())
+	out.WriteByte('\n')
+	if !d.Pos.IsValid() || d.Pos.Offset > len(source) {
+		return out.String()
+	}
+
+	// 找到出错位置所在的整行
+	lineStart := strings.LastIndexByte(source[:d.Pos.Offset], '\n') + 1
+	lineEnd := len(source)
+	if i := strings.IndexByte(source[d.Pos.Offset:], '\n'); i >= 0 {
+		lineEnd = d.Pos.Offset + i
+	}
+	line := strings.TrimRight(source[lineStart:lineEnd], "\r")
+
+	// 下划线的长度为出错范围内的字符数，跨行或者为空时只标出一个字符
+	width := 1
+	if d.End.Line == d.Pos.Line && d.End.Offset > d.Pos.Offset && d.End.Offset <= lineEnd {
+		width = utf8.RuneCountInString(source[d.Pos.Offset:d.End.Offset])
+	}
+
+	// 保留出错位置之前的制表符，保证 ^ 与源码对齐
+	var indent strings.Builder
+	for _, r := range source[lineStart:d.Pos.Offset] {
+		if r == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+
+	gutter := fmt.Sprintf("%d", d.Pos.Line)
+	fmt.Fprintf(&out, "  %s | %s\n", gutter, line)
+	fmt.Fprintf(&out, "  %s | %s%s\n", strings.Repeat(" ", len(gutter)), indent.String(), strings.Repeat("^", width))
+	return out.String()
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"monkey/lexer"
+	"testing"
+)
+
+func TestDiagnosticRender(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x = (1 + 2;",
+			"1:15: error[E0001]: expected next token to be ), got ; instead\n" +
+				"  1 | let x = (1 + 2;\n" +
+				"    |               ^\n",
+		},
+		{
+			"let a = 1;\n\tlet b 10;",
+			"2:8: error[E0001]: expected next token to be =, got INT instead\n" +
+				"  2 | \tlet b 10;\n" +
+				"    | \t      ^^\n",
+		},
+		{
+			"1 +",
+			"1:4: error[E0002]: no prefix parse function for EOF found\n" +
+				"  1 | 1 +\n" +
+				"    |    ^\n",
+		},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Diagnostics()) != 1 {
+			t.Fatalf("input %q: expected 1 diagnostic. got=%v", tt.input, p.Errors())
+		}
+		if got := p.Diagnostics()[0].Render(tt.input); got != tt.expected {
+			t.Errorf("input %q: render wrong.\nexpected=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
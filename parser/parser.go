@@ -1,11 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -24,38 +26,70 @@ const (
 	LOWEST          // 最低优先级定义为 1 也是有用意的, 遇到其他未定义优先级的 token, 则优先级都为 0
 	_               // 赋值表达式左右结合力不同，这里空一个保证赋值运算符优先级总高于 	LOWEST          // 最低优先级定义为 1 也是有用意的, 遇到其他未定义优先级的 token, 则优先级都为 0
 	ASSIGN          // =
+	LOGICAL_OR      // ||
+	LOGICAL_AND     // &&
 	EQUALS          // ==
 	LESSGREATER     // > or <
+	RANGE           // ..
+	BIT_OR          // |
+	BIT_XOR         // ^
+	BIT_AND         // &
+	SHIFT           // << or >>
 	SUM             // +
 	PRODUCT         // *
 	PREFIX          // -X or !X
+	POWER           // **
 	CALL            // myFunction(X)
 	INDEX           // a[i]
 )
 
 // precedences 中缀表达式优先级表
 var precedences = map[token.TokenType]int{
-	token.ASSIGN:   ASSIGN,
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.LTE:      LESSGREATER,
-	token.GTE:      LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.INCREMENT:       INDEX,
+	token.DECREMENT:       INDEX,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LTE:             LESSGREATER,
+	token.GTE:             LESSGREATER,
+	token.DOTDOT:          RANGE,
+	token.PIPE:            BIT_OR,
+	token.CARET:           BIT_XOR,
+	token.AMPERSAND:       BIT_AND,
+	token.SHL:             SHIFT,
+	token.SHR:             SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.DIV:             PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+	token.DOT:             INDEX,
 }
 
 // Parser 是语法解析器，负责将词法单元解析为 AST
 type Parser struct {
-	l         *lexer.Lexer
-	errors    []string
-	curToken  token.Token // 输入中的当前词法单元
-	peekToken token.Token // 下一个词法单元
+	l           *lexer.Lexer
+	diagnostics []*Diagnostic
+	curToken    token.Token // 输入中的当前词法单元
+	peekToken   token.Token // 下一个词法单元
+
+	// recovering 为 true 表示当前语句已经报告过错误
+	// 在同步到语句边界之前不再报告新的错误 避免一个错误引发一连串的错误
+	recovering bool
+	braceDepth int // 当前词元所在的 {} 嵌套深度，用于错误恢复时找到语句块的边界
 
 	prefixParseFns map[token.TokenType]prefixParseFn // 存放处理前缀词法单元的解析函数
 	infixParseFns  map[token.TokenType]infixParseFn  // 存放处理中缀词法单元的解析函数
@@ -71,8 +105,8 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:           l,
+		diagnostics: []*Diagnostic{},
 	}
 	// 初始化前缀解析函数，标识符和字面量部署运算符，属于特殊的前缀解析函数
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
@@ -80,20 +114,33 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE_HEAD, p.parseInterpolatedString)
 	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
 	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
 
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.INCREMENT, p.parsePrefixIncrementExpression) // 解析前缀自增 ++x
+	p.registerPrefix(token.DECREMENT, p.parsePrefixIncrementExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression) // 解析括号表达式
 	p.registerPrefix(token.IF, p.parseIfExpression)          // 解析 if 表达式
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)    // 解析 match 表达式
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral) // 解析数组字面量
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)    // 解析哈希表字面量
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)       // 报告无法识别的字符
 
 	// 初始化中缀表达式解释函数
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.ASSIGN, p.parseAssignExpression) // 解析赋值表达式
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.INCREMENT, p.parseIncrementExpression) // 解析后缀自增 x++
+	p.registerInfix(token.DECREMENT, p.parseIncrementExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
@@ -104,9 +151,21 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.DIV, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)    // 解析函数调用,  把函数调用当作中缀表达式
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression) // 解析数组索引
+	p.registerInfix(token.DOT, p.parseMemberExpression)     // 解析成员访问
 
 	// 读取两个词法单元，以设置curToken和peekToken
 	p.nextToken() // curToken=nil peekToken=第一个 token
@@ -117,6 +176,17 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.COMMENT { // 注释对语法分析没有意义，直接跳过
+		p.peekToken = p.l.NextToken()
+	}
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		if p.braceDepth > 0 {
+			p.braceDepth--
+		}
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -127,6 +197,16 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// peekTokenIsAny 判断下一个词元是否是 ts 中的任意一个
+func (p *Parser) peekTokenIsAny(ts ...token.TokenType) bool {
+	for _, t := range ts {
+		if p.peekTokenIs(t) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) expectPeek(t token.TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -136,8 +216,18 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false
 }
 
+// Errors 以字符串的形式返回所有诊断信息
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, 0, len(p.diagnostics))
+	for _, d := range p.diagnostics {
+		errors = append(errors, d.String())
+	}
+	return errors
+}
+
+// Diagnostics 返回解析过程中产生的所有诊断信息
+func (p *Parser) Diagnostics() []*Diagnostic {
+	return p.diagnostics
 }
 
 // peekPrecedence 下一个词法单元的优先级
@@ -156,16 +246,75 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
-// peekError 向 l.errors 中追加错误信息
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+// errorAt 报告一条位于 tok 处的错误
+// 同一条语句中只有第一个错误会被记录 后续的错误通常是由第一个错误引起的
+func (p *Parser) errorAt(tok token.Token, code string, expected []token.TokenType, format string, a ...interface{}) {
+	if p.recovering {
+		return
+	}
+	p.recovering = true
+	p.diagnostics = append(p.diagnostics, &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Expected: expected,
+	})
+}
+
+// peekError 报告下一个词元不是期望的词元
+func (p *Parser) peekError(expected ...token.TokenType) {
+	if p.peekTokenIs(token.ILLEGAL) {
+		// 词法错误比期望的词元更能说明问题
+		p.illegalTokenError(p.peekToken)
+		return
+	}
+	names := make([]string, 0, len(expected))
+	for _, t := range expected {
+		names = append(names, t.String())
+	}
+	p.errorAt(p.peekToken, CodeUnexpectedToken, expected,
+		"expected next token to be %s, got %s instead",
+		strings.Join(names, " or "), p.peekToken.Type)
+}
+
+// illegalTokenError 报告 ILLEGAL 词元对应的词法错误
+func (p *Parser) illegalTokenError(tok token.Token) {
+	msg := fmt.Sprintf("illegal character %q", tok.Literal)
+	for _, e := range p.l.Errors() {
+		if e.Pos == tok.Pos {
+			msg = e.Msg
+			break
+		}
+	}
+	p.errorAt(tok, CodeIllegalToken, nil, "%s", msg)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, CodeMissingExpression, nil, "no prefix parse function for %s found", t)
+}
+
+// synchronize 在语句出错后跳过剩余的词元 直到 depth 层语句块内的语句边界
+// 返回时 curToken 为出错语句的 ; 或者下一条语句之前的最后一个词元
+// 如果跳过的过程中遇到了所在语句块的 } 则停在 } 上
+func (p *Parser) synchronize(depth int) {
+	defer func() { p.recovering = false }()
+	for !p.curTokenIs(token.EOF) {
+		if p.braceDepth < depth {
+			return
+		}
+		if p.braceDepth == depth {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR, token.TRY, token.THROW, token.RBRACE, token.EOF:
+				return
+			}
+		}
+		p.nextToken()
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -173,7 +322,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 	for p.curToken.Type != token.EOF { // 循环将 Token 读完
 		stmt := p.parseStatement() // Program 由语句组成，循环解析语句
-		if stmt != nil {
+		if p.recovering {
+			// 语句出错 丢弃该语句并跳到下一条语句
+			p.synchronize(0)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		// parseStatement 后当前词元为当前语句的最后一个词元
@@ -191,6 +343,18 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
 	case token.FUNCTION:
 		// 可能是函数申明
 		if p.peekTokenIs(token.IDENT) {
@@ -208,11 +372,21 @@ func (p *Parser) parseStatement() ast.Statement {
 // let <identifier> = <expression>;
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken} // 初始化 let 语句节点
-	// let 语句前两个 token 一定是 IDENT 和 ASSIGN
-	if !p.expectPeek(token.IDENT) {
+	// let 之后是标识符或者解构模式 然后是 ASSIGN
+	if !p.peekTokenIsAny(token.IDENT, token.LBRACKET, token.LBRACE) {
+		p.peekError(token.IDENT)
 		return nil
 	}
-	stmt.Name = p.parseIdentifier().(*ast.Identifier)
+	p.nextToken()
+	pattern := p.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		stmt.Name = ident
+	} else {
+		stmt.Pattern = pattern
+	}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -237,13 +411,148 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+// parseThrowStatement 解析 throw 语句
+// throw <expression>;
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	if stmt.Value = p.parseExpression(LOWEST); stmt.Value == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseTryStatement 解析 try 语句
+// try <blockstatement> catch (<identifier>) <blockstatement> finally <blockstatement>;
+// catch 的括号和标识符可以省略 catch 和 finally 至少需要出现一个
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Param = p.parseIdentifier().(*ast.Identifier)
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.peekError(token.CATCH, token.FINALLY)
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) { // 允许 try 语句后带分号
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseWhileStatement 解析 while 循环语句
+// while (<expression>) <blockstatement>;
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if p.peekTokenIs(token.SEMICOLON) { // 允许 while 语句后带分号
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseForStatement 解析 for 循环语句
+// for (<identifier>[, <identifier>] in <expression>) <blockstatement>;
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variables = append(stmt.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Variables = append(stmt.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if p.peekTokenIs(token.SEMICOLON) { // 允许 for 语句后带分号
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseBreakStatement 解析 break 语句
+// break;
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseContinueStatement 解析 continue 语句
+// continue;
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 // parseFunctionDeclarationStatement 解析 function 申明语句
-// fn <identifier>(<identifier>,...) <blockstatement>
+// fn <identifier>(<identifier>,...) <blockstatement>;
 func (p *Parser) parseFunctionDeclarationStatement() *ast.FunctionDeclarationStatement {
 	stmt := &ast.FunctionDeclarationStatement{
 		Token:      p.curToken,
 		Name:       &ast.Identifier{},
-		Parameters: []*ast.Identifier{},
+		Parameters: []ast.Pattern{},
 	}
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -257,6 +566,10 @@ func (p *Parser) parseFunctionDeclarationStatement() *ast.FunctionDeclarationSta
 		return nil
 	}
 	stmt.Body = p.parseBlockStatement()
+	markTailCalls(stmt.Body)
+	if p.peekTokenIs(token.SEMICOLON) { // 允许函数申明语句后带分号
+		p.nextToken()
+	}
 	return stmt
 }
 
@@ -279,7 +592,11 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		p.noPrefixParseFnError(p.curToken.Type)
 		return nil
 	}
-	leftExp := prefix()
+	return p.parseInfixExpressions(prefix(), precedence)
+}
+
+// parseInfixExpressions 以 leftExp 为左节点 继续解析结合力强于 precedence 的中缀表达式
+func (p *Parser) parseInfixExpressions(leftExp ast.Expression, precedence int) ast.Expression {
 	// 以下循环结构要求
 	// 前缀表达式解析函数: 调用时 curToken=表达式第一个 token, 返回时 curToken=表达式最后一个 token
 	// 中缀表达式解析函数: 调用时 curToken=中缀运算符, 返回时 curToken=表达式最后一个 token
@@ -306,9 +623,12 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorAt(p.curToken, CodeNumberOverflow, nil, "integer literal %s overflows int64", p.curToken.Literal)
+		return nil
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, CodeInvalidNumber, nil, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = value
@@ -319,15 +639,24 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 func (p *Parser) parseFloatLiteral() ast.Expression {
 	fl := &ast.FloatLiteral{Token: p.curToken}
 	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorAt(p.curToken, CodeNumberOverflow, nil, "float literal %s is out of range", p.curToken.Literal)
+		return nil
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, CodeInvalidNumber, nil, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 	fl.Value = value
 	return fl
 }
 
+// parseIllegal 报告词法分析器产生的错误
+func (p *Parser) parseIllegal() ast.Expression {
+	p.illegalTokenError(p.curToken)
+	return nil
+}
+
 // parseBooleanLiteral 布尔字面量解析函数
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	return &ast.BooleanLiteral{
@@ -344,6 +673,38 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+// parseInterpolatedString 插值字符串解析函数
+// 插值中的表达式和普通表达式一样由 parseExpression 解析
+// TEMPLATE_HEAD <expression> TEMPLATE_MIDDLE <expression> ... TEMPLATE_TAIL
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	is := &ast.InterpolatedString{Token: p.curToken}
+	for {
+		if p.curToken.Literal != "" {
+			is.Parts = append(is.Parts, &ast.StringLiteral{
+				Token: p.curToken,
+				Value: p.curToken.Literal,
+			})
+		}
+		if p.curTokenIs(token.TEMPLATE_TAIL) {
+			return is
+		}
+		p.nextToken() // curToken=插值表达式的第一个 token
+		is.Parts = append(is.Parts, p.parseExpression(LOWEST))
+		if p.peekTokenIs(token.ILLEGAL) {
+			// 与 peekError 相同 优先报告词法错误 例如未闭合的字符串
+			p.illegalTokenError(p.peekToken)
+			return nil
+		}
+		if !p.peekTokenIs(token.TEMPLATE_MIDDLE) && !p.peekTokenIs(token.TEMPLATE_TAIL) {
+			p.errorAt(p.peekToken, CodeUnexpectedToken,
+				[]token.TokenType{token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL},
+				"expected } to close string interpolation, got %s instead", p.peekToken.Type)
+			return nil
+		}
+		p.nextToken()
+	}
+}
+
 // parsePrefixExpression 前缀表达式解析函数
 // -<expression>
 // !<expression>
@@ -375,6 +736,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Right:    nil,
 	}
 	precedence := p.curPrecedence()
+	if ie.Token.Type == token.POWER {
+		// ** 是右结合的 降低右结合力保证 2 ** 3 ** 2 解析为 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	ie.Right = p.parseExpression(precedence)
 	return ie
@@ -420,6 +785,165 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return ie
 }
 
+// parseMatchExpression 解析 match 表达式
+// match (<expression>) { <pattern> [if <expression>] => <expression>, <pattern> => <blockstatement> ... }
+// 分支之间用逗号分隔 以语句块结尾的分支之后可以省略逗号
+func (p *Parser) parseMatchExpression() ast.Expression {
+	me := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	me.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		me.Arms = append(me.Arms, arm)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.curTokenIs(token.RBRACE) {
+			break
+		}
+	}
+	if !p.peekTokenIs(token.RBRACE) {
+		p.peekError(token.COMMA, token.RBRACE)
+		return nil
+	}
+	p.nextToken()
+	return me
+}
+
+// parseMatchArm 解析 match 表达式的一个分支 调用时 curToken 为模式的第一个词元
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parseMatchPattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		if arm.Guard = p.parseExpression(LOWEST); arm.Guard == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		if arm.Body = p.parseMatchArmBlock(); arm.Body == nil {
+			return nil
+		}
+		return arm
+	}
+	tok := p.curToken
+	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+	arm.Body = &ast.BlockStatement{
+		Token:      tok,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: tok, Expression: exp}},
+	}
+	return arm
+}
+
+// parseMatchArmBlock 解析以 { 开头的分支体 按以下规则区分语句块和哈希表字面量
+//   - {} 是空语句块 结果为 null 空哈希表需要写成 ({})
+//   - { 之后是 let return while 等语句关键字时 按语句块解析
+//   - 否则先解析第一个表达式 表达式之后的词元是 : 时按哈希表字面量解析 例如 {"k": v}
+//     哈希表之后可以继续接运算符 例如 {"k": v}["k"]
+//   - 其余情况按语句块解析 第一个表达式是第一条语句 例如 { x } 和 { "a" }
+//
+// 换行不影响判断 { "a"\n: 1 } 同样是哈希表字面量 而语句块的 } 之后出现的 : 是语法错误
+func (p *Parser) parseMatchArmBlock() *ast.BlockStatement {
+	if p.peekTokenIsAny(token.RBRACE, token.LET, token.RETURN, token.WHILE, token.FOR,
+		token.BREAK, token.CONTINUE, token.THROW, token.TRY, token.FUNCTION) {
+		return p.parseBlockStatement()
+	}
+	bs := &ast.BlockStatement{
+		Token:      p.curToken,
+		Statements: []ast.Statement{},
+	}
+	depth := p.braceDepth
+	p.nextToken()
+	stmt := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+	if stmt.Expression != nil && p.peekTokenIs(token.COLON) {
+		hl := &ast.HashLiteral{Token: bs.Token, Pairs: map[ast.Expression]ast.Expression{}}
+		if p.parseHashPairs(hl, stmt.Expression) == nil {
+			return nil
+		}
+		// 哈希表字面量之后还可以继续接运算符 例如 {"a": 1}["a"]
+		exp := p.parseInfixExpressions(hl, LOWEST)
+		bs.Statements = append(bs.Statements, &ast.ExpressionStatement{Token: bs.Token, Expression: exp})
+		return bs
+	}
+	// 第一个表达式是语句块中的第一条语句
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if p.recovering {
+		p.synchronize(depth)
+		if p.braceDepth < depth {
+			return bs
+		}
+	} else {
+		bs.Statements = append(bs.Statements, stmt)
+	}
+	p.nextToken()
+	p.parseBlockBody(bs, depth)
+	return bs
+}
+
+// parseMatchPattern 解析 match 分支的模式 多个模式可以用 | 连接
+// <pattern> | <pattern> | ...
+func (p *Parser) parseMatchPattern() ast.Pattern {
+	first := p.parseSingleMatchPattern()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+	ap := &ast.AlternativePattern{Token: p.peekToken, Alternatives: []ast.Pattern{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		alt := p.parseSingleMatchPattern()
+		if alt == nil {
+			return nil
+		}
+		ap.Alternatives = append(ap.Alternatives, alt)
+	}
+	return ap
+}
+
+// parseSingleMatchPattern 解析不含 | 的单个模式
+// 字面量 1 -1 "a" true、绑定变量的标识符、通配符 _、数组模式、哈希表模式
+// 数组模式和哈希表模式中的元素同样可以是任意的 match 模式
+func (p *Parser) parseSingleMatchPattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		lp := &ast.LiteralPattern{Token: p.curToken}
+		// | 用于连接多个模式 所以字面量不能包含 | 以及优先级更低的运算符
+		if lp.Value = p.parseExpression(BIT_OR); lp.Value == nil {
+			return nil
+		}
+		return lp
+	case token.LBRACKET:
+		return p.parseArrayPattern(p.parseMatchPattern)
+	case token.LBRACE:
+		return p.parseHashPattern(p.parseMatchPattern)
+	}
+	return p.parsePattern()
+}
+
 // parseBlockStatement 解析语句块 {}
 // { <statement>;... }
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -427,12 +951,32 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		Token:      p.curToken,
 		Statements: []ast.Statement{},
 	}
+	depth := p.braceDepth
 	p.nextToken() // 指向 { 的下一个 token
+	p.parseBlockBody(bs, depth)
+	return bs
+}
+
+// parseBlockBody 解析语句块中剩余的语句 直到语句块的 }
+// 调用时 curToken 为下一条语句的第一个词元 depth 为语句块所在的花括号层数
+func (p *Parser) parseBlockBody(bs *ast.BlockStatement, depth int) {
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		bs.Statements = append(bs.Statements, p.parseStatement())
+		stmt := p.parseStatement()
+		if p.recovering {
+			p.synchronize(depth)
+			if p.braceDepth < depth {
+				// 同步时已经读到了语句块的 }
+				break
+			}
+		} else if stmt != nil {
+			bs.Statements = append(bs.Statements, stmt)
+		}
 		p.nextToken()
 	}
-	return bs
+	if p.curTokenIs(token.EOF) {
+		p.errorAt(p.curToken, CodeUnexpectedToken, []token.TokenType{token.RBRACE},
+			"expected } to close block, got %s instead", p.curToken.Type)
+	}
 }
 
 // parseFunctionLiteral 函数字面量解析函数
@@ -441,7 +985,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	fl := &ast.FunctionLiteral{
 		Token:      p.curToken,
-		Parameters: []*ast.Identifier{},
+		Parameters: []ast.Pattern{},
 	}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -451,31 +995,156 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 	fl.Body = p.parseBlockStatement()
+	markTailCalls(fl.Body)
 	return fl
 }
 
 // parseFunctionParameters 解析函数形参列表, (a,b,c) () (a)
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	ids := []*ast.Identifier{}
+// 形参可以是解构模式 fn([a, b], {name})
+// 可以带有默认值 fn(a, b = 2) 带默认值的形参之后不能再出现不带默认值的形参
+// 最后一个形参可以是可变参数 fn(a, ...rest)
+func (p *Parser) parseFunctionParameters() []ast.Pattern {
+	params := []ast.Pattern{}
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return ids
+		return params
 	}
-	if !p.expectPeek(token.IDENT) {
+	hasDefault := false
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			rest := &ast.RestPattern{Token: p.curToken}
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			rest.Name = p.parseIdentifier().(*ast.Identifier)
+			params = append(params, rest)
+			if !p.peekTokenIs(token.RPAREN) {
+				p.peekError(token.RPAREN)
+				return nil
+			}
+			break
+		}
+		if !p.peekTokenIsAny(token.IDENT, token.LBRACKET, token.LBRACE) {
+			p.peekError(token.IDENT)
+			return nil
+		}
+		p.nextToken()
+		param := p.parsePattern()
+		if param == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			dp := &ast.DefaultPattern{Token: p.curToken, Pattern: param}
+			p.nextToken()
+			// 默认值中不允许出现赋值表达式 fn(a = b = 1) 是错误的
+			if dp.Default = p.parseExpression(ASSIGN); dp.Default == nil {
+				return nil
+			}
+			param = dp
+			hasDefault = true
+		} else if hasDefault {
+			p.errorAt(p.curToken, CodeUnexpectedToken, []token.TokenType{token.ASSIGN},
+				"non-default parameter %s follows default parameter", param.String())
+			return nil
+		}
+		params = append(params, param)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.peekTokenIs(token.RPAREN) {
+		p.peekError(token.COMMA, token.RPAREN)
 		return nil
 	}
-	ids = append(ids, p.parseIdentifier().(*ast.Identifier))
-	for p.peekTokenIs(token.COMMA) {
+	p.nextToken()
+	return params
+}
+
+// parsePattern 解析用于绑定变量的模式 调用时 curToken 为模式的第一个词元
+// <identifier>
+// [<pattern>, ..., ...<identifier>]
+// {<identifier>, <identifier>: <pattern>, ...}
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return p.parseIdentifier().(*ast.Identifier)
+	case token.LBRACKET:
+		return p.parseArrayPattern(p.parsePattern)
+	case token.LBRACE:
+		return p.parseHashPattern(p.parsePattern)
+	}
+	p.errorAt(p.curToken, CodeUnexpectedToken, []token.TokenType{token.IDENT, token.LBRACKET, token.LBRACE},
+		"expected pattern, got %s instead", p.curToken.Type)
+	return nil
+}
+
+// parseArrayPattern 解析数组模式 ...rest 只能出现在最后
+// 数组中的每个元素由 parseElement 解析
+func (p *Parser) parseArrayPattern(parseElement func() ast.Pattern) ast.Pattern {
+	ap := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			ap.Rest = p.parseIdentifier().(*ast.Identifier)
+			break
+		}
+		elem := parseElement()
+		if elem == nil {
+			return nil
+		}
+		ap.Elements = append(ap.Elements, elem)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
+	}
+	if !p.peekTokenIs(token.RBRACKET) {
+		if ap.Rest != nil {
+			p.peekError(token.RBRACKET)
+		} else {
+			p.peekError(token.COMMA, token.RBRACKET)
+		}
+		return nil
+	}
+	p.nextToken()
+	return ap
+}
+
+// parseHashPattern 解析哈希表模式 {name} 是 {name: name} 的简写
+// 冒号之后的模式由 parseElement 解析
+func (p *Parser) parseHashPattern(parseElement func() ast.Pattern) ast.Pattern {
+	hp := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
 		if !p.expectPeek(token.IDENT) {
 			return nil
 		}
-		ids = append(ids, p.parseIdentifier().(*ast.Identifier))
+		key := p.parseIdentifier().(*ast.Identifier)
+		var value ast.Pattern = key
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if value = parseElement(); value == nil {
+				return nil
+			}
+		}
+		hp.Pairs = append(hp.Pairs, ast.HashPatternPair{Key: key, Value: value})
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
-	if !p.expectPeek(token.RPAREN) {
+	if !p.peekTokenIs(token.RBRACE) {
+		p.peekError(token.COMMA, token.RBRACE)
 		return nil
 	}
-	return ids
+	p.nextToken()
+	return hp
 }
 
 // parseCallExpression 函数调用解析函数, 函数调用是一种中缀表达式, 左边的表达式是函数
@@ -490,42 +1159,87 @@ func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 }
 
 // parseCallExpressionArguments 解析函数实参列表, (a,b,c) () (a) (a+1, b, 3)
+// 关键字参数 (a, port: 5432) 必须位于所有位置参数之后
 func (p *Parser) parseCallExpressionArguments() []ast.Expression {
-	args := []ast.Expression{}
+	keyword := false
+	return p.parseList(token.RPAREN, func() ast.Expression {
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			keyword = true
+			return p.parseKeywordArgument()
+		}
+		if keyword {
+			p.errorAt(p.curToken, CodeUnexpectedToken, nil, "positional argument follows keyword argument")
+		}
+		return p.parseListElement()
+	})
+}
+
+// parseKeywordArgument 解析关键字参数 调用时 curToken 为参数名
+// <identifier>: <expression>
+func (p *Parser) parseKeywordArgument() ast.Expression {
+	ka := &ast.KeywordArgument{
+		Token: p.curToken,
+		Name:  p.parseIdentifier().(*ast.Identifier),
+	}
+	p.nextToken() // curToken=COLON
 	p.nextToken()
-	if p.curTokenIs(token.RPAREN) {
-		return args
+	if ka.Value = p.parseExpression(LOWEST); ka.Value == nil {
+		return nil
+	}
+	return ka
+}
+
+// parseExpressionList 解析以逗号分隔、以 end 结尾的表达式列表
+// 列表中的元素可以是展开表达式 ...xs
+// 调用时 curToken 为列表的起始词元, 返回时 curToken 为 end
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	return p.parseList(end, p.parseListElement)
+}
+
+// parseList 解析以逗号分隔、以 end 结尾的列表 每个元素由 parseElement 解析
+// 调用 parseElement 时 curToken 为元素的第一个词元
+func (p *Parser) parseList(end token.TokenType, parseElement func() ast.Expression) []ast.Expression {
+	list := []ast.Expression{}
+	p.nextToken()
+	if p.curTokenIs(end) {
+		return list
 	}
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, parseElement())
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() // curToken=COMMA
 		p.nextToken() // curToken=表达式第一个 token
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, parseElement())
 	}
-	if !p.expectPeek(token.RPAREN) {
+	if !p.peekTokenIs(end) {
+		p.peekError(token.COMMA, end)
 		return nil
 	}
-	return args
+	p.nextToken()
+	return list
 }
 
-// parseArrayLiteral 解析数组字面量
-func (p *Parser) parseArrayLiteral() ast.Expression {
-	al := &ast.ArrayLiteral{
-		Token:    p.curToken,
-		Elements: []ast.Expression{},
+// parseListElement 解析表达式列表中的一个元素
+// ...<expression>
+// <expression>
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
 	}
+	se := &ast.SpreadExpression{Token: p.curToken}
 	p.nextToken()
-	if p.curTokenIs(token.RBRACKET) {
-		return al
+	if se.Value = p.parseExpression(LOWEST); se.Value == nil {
+		return nil
 	}
-	al.Elements = append(al.Elements, p.parseExpression(LOWEST))
+	return se
+}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken() // curToken=COMMA
-		p.nextToken() // curToken=表达式第一个 token
-		al.Elements = append(al.Elements, p.parseExpression(LOWEST))
+// parseArrayLiteral 解析数组字面量
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	al := &ast.ArrayLiteral{
+		Token: p.curToken,
 	}
-	if !p.expectPeek(token.RBRACKET) {
+	al.Elements = p.parseExpressionList(token.RBRACKET)
+	if al.Elements == nil {
 		return nil
 	}
 	return al
@@ -537,13 +1251,74 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		Left:  left,
 	}
 	p.nextToken()
+	if p.curTokenIs(token.COLON) {
+		// 省略了起始位置的切片 a[:end]
+		return p.parseSliceExpression(ie.Token, left, nil)
+	}
 	ie.Index = p.parseExpression(LOWEST)
-	if !p.expectPeek(token.RBRACKET) {
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(ie.Token, left, ie.Index)
+	}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.peekError(token.RBRACKET, token.COLON)
 		return nil
 	}
+	p.nextToken()
 	return ie
 }
 
+// parseSliceExpression 解析切片表达式 调用时 curToken 为起始位置之后的 :
+// <expression>[<expression>?:<expression>?]
+// <expression>[<expression>?:<expression>?:<expression>?]
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	se := &ast.SliceExpression{
+		Token: tok,
+		Left:  left,
+		Start: start,
+	}
+	var ok bool
+	if se.End, ok = p.parseSliceBound(token.COLON, token.RBRACKET); !ok {
+		return nil
+	}
+	if p.curTokenIs(token.COLON) {
+		if se.Step, ok = p.parseSliceBound(token.RBRACKET); !ok {
+			return nil
+		}
+	}
+	return se
+}
+
+// parseSliceBound 解析切片中 : 之后可以省略的一个部分
+// 返回时 curToken 为该部分之后的词元，只能是 ends 中的一个
+func (p *Parser) parseSliceBound(ends ...token.TokenType) (ast.Expression, bool) {
+	var exp ast.Expression
+	if !p.peekTokenIsAny(ends...) {
+		p.nextToken()
+		exp = p.parseExpression(LOWEST)
+	}
+	if !p.peekTokenIsAny(ends...) {
+		p.peekError(ends...)
+		return nil, false
+	}
+	p.nextToken()
+	return exp, true
+}
+
+// parseMemberExpression 解析成员访问表达式
+// <expression>.<identifier>
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	me := &ast.MemberExpression{
+		Token:  p.curToken,
+		Object: left,
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	me.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return me
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hl := &ast.HashLiteral{
 		Token: p.curToken,
@@ -553,31 +1328,41 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if p.curTokenIs(token.RBRACE) {
 		return hl
 	}
+	return p.parseHashPairs(hl, p.parseExpression(LOWEST))
+}
 
-	key, val, ok := p.parseKeyValPair()
+// parseHashPairs 解析哈希表字面量第一个键之后的部分
+// 调用时 curToken 为第一个键的最后一个词元 返回后 curToken 为 }
+func (p *Parser) parseHashPairs(hl *ast.HashLiteral, key ast.Expression) ast.Expression {
+	val, ok := p.parseHashValue()
 	if !ok {
 		return nil
 	}
 	hl.Pairs[key] = val
+	hl.Keys = append(hl.Keys, key)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		key, val, ok = p.parseKeyValPair()
+		key = p.parseExpression(LOWEST)
+		val, ok = p.parseHashValue()
 		if !ok {
 			return nil
 		}
 		hl.Pairs[key] = val
+		hl.Keys = append(hl.Keys, key)
 	}
 
-	if !p.expectPeek(token.RBRACE) {
+	if !p.peekTokenIs(token.RBRACE) {
+		p.peekError(token.COMMA, token.RBRACE)
 		return nil
 	}
+	p.nextToken()
 	return hl
 }
 
-func (p *Parser) parseKeyValPair() (key, val ast.Expression, ok bool) {
-	key = p.parseExpression(LOWEST)
+// parseHashValue 解析键之后的 : 和值
+func (p *Parser) parseHashValue() (val ast.Expression, ok bool) {
 	if !p.expectPeek(token.COLON) {
 		return
 	}
@@ -590,13 +1375,43 @@ func (p *Parser) parseKeyValPair() (key, val ast.Expression, ok bool) {
 // parseAssignExpression 赋值表达式解析函数
 // <identifier> = <expression>
 // <expression> = <identifier> = <expression>
+// <expression> += <expression>
 func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	ae := &ast.AssignExpression{
-		Token: p.curToken,
-		Left:  left,
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
 	}
 	p.nextToken()
 	// 降低 = 的右结合力 保证连等赋值时 从右往左赋值
 	ae.Value = p.parseExpression(ASSIGN - 1)
 	return ae
 }
+
+// parsePrefixIncrementExpression 解析前缀自增自减表达式
+// ++<expression>
+// --<expression>
+func (p *Parser) parsePrefixIncrementExpression() ast.Expression {
+	ae := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Prefix:   true,
+	}
+	p.nextToken()
+	ae.Left = p.parseExpression(PREFIX)
+	if ae.Left == nil {
+		return nil
+	}
+	return ae
+}
+
+// parseIncrementExpression 解析后缀自增自减表达式
+// <expression>++
+// <expression>--
+func (p *Parser) parseIncrementExpression(left ast.Expression) ast.Expression {
+	return &ast.AssignExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
+	"reflect"
 	"testing"
 )
 
@@ -106,6 +108,24 @@ func TestFunctionDeclarationStatement(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionDeclarationStatementSemicolon(t *testing.T) {
+	input := `fn foo(x) { x; }; foo(1);`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			2, len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.FunctionDeclarationStatement); !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionDeclarationStatement. got=%T", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Fatalf("program.Statements[1] is not ast.ExpressionStatement. got=%T", program.Statements[1])
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -322,6 +342,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~15;", "~", 15},
 	}
 	for _, tt := range prefixTests {
 		l := lexer.New(tt.input)
@@ -370,6 +391,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"a || b", "a", "||", "b"},
+		{"5 % 5;", 5, "%", 5},
+		{"5 div 5;", 5, "div", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 	}
 	for _, tt := range infixTests {
 		l := lexer.New(tt.input)
@@ -526,6 +557,118 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a[1], b[add(2)])",
 			"add((a[1]), (b[add(2)]))",
 		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b << c + d",
+			"(a & (b << (c + d)))",
+		},
+		{
+			"a & b == c | d",
+			"((a & b) == (c | d))",
+		},
+		{
+			"~a & -b",
+			"((~a) & (-b))",
+		},
+		{
+			"a[1:2]",
+			"(a[1:2])",
+		},
+		{
+			"a[:b + 1][::-1]",
+			"((a[:(b + 1)])[::(-1)])",
+		},
+		{
+			"a[1:][i:j:2]",
+			"((a[1:])[i:j:2])",
+		},
+		{
+			"a[:]",
+			"(a[:])",
+		},
+		{
+			"a += b * c",
+			"(a+=(b * c))",
+		},
+		{
+			"a = b -= c",
+			"(a=(b-=c))",
+		},
+		{
+			"a[i] %= 2",
+			"((a[i])%=2)",
+		},
+		{
+			"-a.b++ + 1",
+			"((-((a.b)++)) + 1)",
+		},
+		{
+			"++a.b * 2",
+			"((++(a.b)) * 2)",
+		},
+		{
+			"-a[0] + --x",
+			"((-(a[0])) + (--x))",
+		},
+		{
+			"a-- - 1",
+			"((a--) - 1)",
+		},
+		{
+			"a++ + 1",
+			"((a++) + 1)",
+		},
+		{
+			"5 - -3",
+			"(5 - (-3))",
+		},
+		{
+			"a.b.c",
+			"((a.b).c)",
+		},
+		{
+			"a.b(1).c[0]",
+			"(((a.b)(1).c)[0])",
+		},
+		{
+			"-a.b * c",
+			"((-(a.b)) * c)",
+		},
+		{
+			"a.b = c.d",
+			"((a.b)=(c.d))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a * b ** c % d div e",
+			"(((a * (b ** c)) % d) div e)",
+		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a = b || c",
+			"(a=(b || c))",
+		},
 		{
 			"add()[1]",
 			"(add()[1])",
@@ -534,6 +677,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add[1]()",
 			"(add[1])()",
 		},
+		{
+			"a + /* inline */ b // trailing",
+			"(a + b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -585,6 +732,411 @@ func TestIfExpression(t *testing.T) {
 	}
 }
 
+func TestDestructuringPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, [b, c], ...rest] = xs;", "let [a, [b, c], ...rest] = xs;"},
+		{"let [...all] = xs;", "let [...all] = xs;"},
+		{"let [] = xs;", "let [] = xs;"},
+		{"let {name, age: years} = user;", "let {name, age: years} = user;"},
+		{"let {pos: [x, y], meta: {id}} = item;", "let {pos: [x, y], meta: {id}} = item;"},
+		{"fn([a, b], {c}) { a }", "fn([a, b], {c}) a"},
+		{"fn f(x, [y, ...z]) { y }", "fn f(x, [y, ...z]) y"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("let [a, b] = xs;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.LetStatement)
+	if stmt.Name != nil {
+		t.Errorf("stmt.Name should be nil for destructuring let. got=%s", stmt.Name)
+	}
+	pattern, ok := stmt.Pattern.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("stmt.Pattern is not ast.ArrayPattern. got=%T", stmt.Pattern)
+	}
+	if len(pattern.Elements) != 2 || pattern.Rest != nil {
+		t.Fatalf("pattern has wrong shape. got=%s", pattern)
+	}
+	testIdentifier(t, pattern.Elements[0], "a")
+	testIdentifier(t, pattern.Elements[1], "b")
+}
+
+func TestDefaultRestAndSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 2) { a }", "fn(a, b = 2) a"},
+		{"fn(a, b = a * 2, ...rest) { a }", "fn(a, b = (a * 2), ...rest) a"},
+		{"fn f(...args) { args }", "fn f(...args) args"},
+		{"fn([a, b] = [1, 2]) { a }", "fn([a, b] = [1, 2]) a"},
+		{"f(...xs)", "f(...xs)"},
+		{"f(1, ...xs, ...ys.map(g))", "f(1, ...xs, ...(ys.map)(g))"},
+		{"[0, ...xs, 4]", "[0, ...xs, 4]"},
+		{"[...a + b]", "[...(a + b)]"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestKeywordArguments(t *testing.T) {
+	input := `connect(db, port: 5432, opts: {"a": 1})`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if len(call.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(call.Arguments))
+	}
+	testLiteralExpression(t, call.Arguments[0], "db")
+	kw, ok := call.Arguments[1].(*ast.KeywordArgument)
+	if !ok {
+		t.Fatalf("call.Arguments[1] is not ast.KeywordArgument. got=%T", call.Arguments[1])
+	}
+	testIdentifier(t, kw.Name, "port")
+	testLiteralExpression(t, kw.Value, 5432)
+	if _, ok := call.Arguments[2].(*ast.KeywordArgument); !ok {
+		t.Fatalf("call.Arguments[2] is not ast.KeywordArgument. got=%T", call.Arguments[2])
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(a: 1, b: x + 1)", "f(a: 1, b: (x + 1))"},
+		{"f(...xs, c: 3)", "f(...xs, c: 3)"},
+		{"[a, b]", "[a, b]"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 1 | 2 => "small", [a, b] if a > b => a, {kind: "a"} => { 1; 2 }, _ => 0 }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	me, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, me.Subject, "x")
+	if len(me.Arms) != 4 {
+		t.Fatalf("wrong number of arms. got=%d", len(me.Arms))
+	}
+	alt, ok := me.Arms[0].Pattern.(*ast.AlternativePattern)
+	if !ok {
+		t.Fatalf("arm 0 pattern is not ast.AlternativePattern. got=%T", me.Arms[0].Pattern)
+	}
+	if len(alt.Alternatives) != 2 {
+		t.Fatalf("wrong number of alternatives. got=%d", len(alt.Alternatives))
+	}
+	if _, ok := alt.Alternatives[0].(*ast.LiteralPattern); !ok {
+		t.Fatalf("alternative 0 is not ast.LiteralPattern. got=%T", alt.Alternatives[0])
+	}
+	if me.Arms[1].Guard == nil {
+		t.Fatalf("arm 1 should have a guard")
+	}
+	testInfixExpression(t, me.Arms[1].Guard, "a", ">", "b")
+	if len(me.Arms[2].Body.Statements) != 2 {
+		t.Fatalf("arm 2 body should have 2 statements. got=%d", len(me.Arms[2].Body.Statements))
+	}
+	testIdentifier(t, me.Arms[3].Pattern, "_")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { -1 => a }", "match (x) { (-1) => a }"},
+		{`match (x) { [1, [y, _], ...rest] => y, }`, "match (x) { [1, [y, _], ...rest] => y }"},
+		{`match (x) { {pos: [0, y] | [y, 0]} => y }`, "match (x) { {pos: [0, y] | [y, 0]} => y }"},
+		{"match (x) { n if n > 0 => { n } _ => 0 }", "match (x) { n if (n > 0) => n, _ => 0 }"},
+		{"let y = match (x) { true => 1, false => 0 };", "let y = match (x) { true => 1, false => 0 };"},
+		// => 之后的 { 在第一个表达式后紧跟 : 时是哈希表字面量
+		{`match (x) { 1 => {"a": 1, "b": 2}, _ => {} }`, "match (x) { 1 => {a:1, b:2}, _ =>  }"},
+		{"match (x) { 1 => {a; b}, _ => { f(x) } }", "match (x) { 1 => a b, _ => f(x) }"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMatchArmBody(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // 分支体中唯一一条语句的表达式类型 空字符串表示空语句块
+	}{
+		{"match (x) { _ => {} }", ""},
+		{"match (x) { _ => { } }", ""},
+		{"match (x) { _ => ({}) }", "*ast.HashLiteral"},
+		{"match (x) { _ => { x } }", "*ast.Identifier"},
+		{"match (x) { _ => { x; } }", "*ast.Identifier"},
+		{`match (x) { _ => { "a" } }`, "*ast.StringLiteral"},
+		{`match (x) { _ => { "k": v } }`, "*ast.HashLiteral"},
+		{"match (x) { _ => { k: v } }", "*ast.HashLiteral"},
+		{"match (x) { _ => { \"a\"\n: 1 } }", "*ast.HashLiteral"},
+		{`match (x) { _ => { "k": v }["k"] }`, "*ast.IndexExpression"},
+		{"match (x) { _ => { f(x) } }", "*ast.CallExpression"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		me := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+		body := me.Arms[0].Body
+		if tt.expected == "" {
+			if len(body.Statements) != 0 {
+				t.Errorf("arm body of %q should be empty. got=%d statements", tt.input, len(body.Statements))
+			}
+			continue
+		}
+		if len(body.Statements) != 1 {
+			t.Errorf("arm body of %q should have 1 statement. got=%d", tt.input, len(body.Statements))
+			continue
+		}
+		stmt, ok := body.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Errorf("arm body statement of %q is not ast.ExpressionStatement. got=%T", tt.input, body.Statements[0])
+			continue
+		}
+		if got := fmt.Sprintf("%T", stmt.Expression); got != tt.expected {
+			t.Errorf("arm body of %q wrong. expected=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+
+	// 语句块的 } 之后出现 : 是语法错误
+	p := New(lexer.New("match (x) { _ => { \"a\" }\n: 1 }"))
+	p.ParseProgram()
+	if len(p.Diagnostics()) == 0 {
+		t.Errorf("expected diagnostics for : after an arm block")
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	input := `try { f(); } catch (e) { e } finally { g(); }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.TryStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Block.Statements) != 1 {
+		t.Errorf("try block should have 1 statement. got=%d", len(stmt.Block.Statements))
+	}
+	testIdentifier(t, stmt.Param, "e")
+	if stmt.Catch == nil || stmt.Finally == nil {
+		t.Fatalf("catch and finally should not be nil")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a } catch { b }", "try a catch b"},
+		{"try { a } finally { b }", "try a finally b"},
+		{"try { a } catch (e) { b }; c", "try a catch (e) bc"},
+		{"throw x + 1;", "throw (x + 1);"},
+		{`throw {"kind": "E"}`, "throw {kind:E};"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTailCallMarking(t *testing.T) {
+	tests := []struct {
+		input string
+		tail  map[string]bool // 被调用的函数名 是否为尾调用
+	}{
+		{"fn(x) { f(x) }", map[string]bool{"f": true}},
+		{"fn(x) { f(x); g(x) }", map[string]bool{"f": false, "g": true}},
+		{"fn(x) { return f(x); }", map[string]bool{"f": true}},
+		{"fn(x) { h(f(x)) }", map[string]bool{"h": true, "f": false}},
+		{"fn(x) { 1 + f(x) }", map[string]bool{"f": false}},
+		{"fn(x) { let y = f(x); y }", map[string]bool{"f": false}},
+		{"fn(x) { if (x) { f(x) } else { g(x) } }", map[string]bool{"f": true, "g": true}},
+		{"fn(x) { if (x) { f(x) }; 1 }", map[string]bool{"f": false}},
+		{"fn(x) { if (x) { return f(x); }; 1 }", map[string]bool{"f": true}},
+		{"fn(x) { match (x) { 1 => f(x), _ => { g(x) } } }", map[string]bool{"f": true, "g": true}},
+		{"fn(x) { while (x) { f(x) } }", map[string]bool{"f": false}},
+		{"fn(x) { for (i in x) { return f(i); } }", map[string]bool{"f": true}},
+		{"fn(x) { try { return f(x); } catch { g(x) } }", map[string]bool{"f": false, "g": false}},
+		{"fn f(x) { g(x) }", map[string]bool{"g": true}},
+		{"f(x)", map[string]bool{"f": false}},
+		{"fn(x) { fn(y) { 1 }; f(x) }", map[string]bool{"f": true}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		got := map[string]bool{}
+		collectCalls(program, got)
+		if !reflect.DeepEqual(got, tt.tail) {
+			t.Errorf("wrong tail calls for %q. expected=%v, got=%v", tt.input, tt.tail, got)
+		}
+	}
+}
+
+// collectCalls 收集 node 中所有以标识符调用的函数调用及其 Tail 标记
+func collectCalls(node ast.Node, calls map[string]bool) {
+	switch n := node.(type) {
+	case *ast.Program:
+		for _, s := range n.Statements {
+			collectCalls(s, calls)
+		}
+	case *ast.BlockStatement:
+		if n == nil {
+			return
+		}
+		for _, s := range n.Statements {
+			collectCalls(s, calls)
+		}
+	case *ast.ExpressionStatement:
+		collectCalls(n.Expression, calls)
+	case *ast.ReturnStatement:
+		collectCalls(n.ReturnValue, calls)
+	case *ast.LetStatement:
+		collectCalls(n.Value, calls)
+	case *ast.FunctionDeclarationStatement:
+		collectCalls(n.Body, calls)
+	case *ast.WhileStatement:
+		collectCalls(n.Body, calls)
+	case *ast.ForStatement:
+		collectCalls(n.Body, calls)
+	case *ast.TryStatement:
+		collectCalls(n.Block, calls)
+		collectCalls(n.Catch, calls)
+	case *ast.FunctionLiteral:
+		collectCalls(n.Body, calls)
+	case *ast.InfixExpression:
+		collectCalls(n.Left, calls)
+		collectCalls(n.Right, calls)
+	case *ast.IfExpression:
+		collectCalls(n.Consequence, calls)
+		collectCalls(n.Alternative, calls)
+	case *ast.MatchExpression:
+		for _, arm := range n.Arms {
+			collectCalls(arm.Body, calls)
+		}
+	case *ast.CallExpression:
+		if ident, ok := n.Function.(*ast.Identifier); ok {
+			calls[ident.Value] = n.Tail
+		}
+		for _, arg := range n.Arguments {
+			collectCalls(arg, calls)
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { if (x == 5) { break; } continue };`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Fatalf("Statements[1] is not ast.ContinueStatement. got=%T", stmt.Body.Statements[1])
+	}
+	ifExp := stmt.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if _, ok := ifExp.Consequence.Statements[0].(*ast.BreakStatement); !ok {
+		t.Fatalf("consequence is not ast.BreakStatement. got=%T", ifExp.Consequence.Statements[0])
+	}
+	if program.String() != "while(x < y) if(x == 5) break; continue;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input     string
+		variables []string
+		expected  string
+	}{
+		{"for (x in xs) { x }", []string{"x"}, "for(x in xs) x"},
+		{"for (k, v in h) { k; v }", []string{"k", "v"}, "for(k, v in h) k v"},
+		{"for (i in 0..n + 1) { }", []string{"i"}, "for(i in (0 .. (n + 1))) "},
+		{"for (x in xs) { x };", []string{"x"}, "for(x in xs) x"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if len(stmt.Variables) != len(tt.variables) {
+			t.Fatalf("wrong number of variables. expected=%d, got=%d", len(tt.variables), len(stmt.Variables))
+		}
+		for i, name := range tt.variables {
+			testIdentifier(t, stmt.Variables[i], name)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestIfElseExpression(t *testing.T) {
 	input := `if (x < y) { x } else { y }`
 	l := lexer.New(input)
@@ -811,3 +1363,190 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestNodePositions(t *testing.T) {
+	input := `let a = 1;
+foo(a + 2)[0];`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			2, len(program.Statements))
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	index := stmt.Expression.(*ast.IndexExpression)
+	call := index.Left.(*ast.CallExpression)
+	infix := call.Arguments[0].(*ast.InfixExpression)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "1:1"},
+		{let, "1:1"},
+		{let.Name, "1:5"},
+		{let.Value, "1:9"},
+		{stmt, "2:1"},
+		{index, "2:1"},
+		{call, "2:1"},
+		{infix, "2:5"},
+		{infix.Right, "2:9"},
+	}
+	for i, tt := range tests {
+		if got := tt.node.Pos().String(); got != tt.expected {
+			t.Errorf("tests[%d] - %T position wrong. expected=%q, got=%q",
+				i, tt.node, tt.expected, got)
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedCode     string
+		expectedPos      string
+		expectedMessage  string
+		expectedExpected []token.TokenType
+	}{
+		{"let x 5;", CodeUnexpectedToken, "1:7", "expected next token to be =, got INT instead", []token.TokenType{token.ASSIGN}},
+		{"let = 5;", CodeUnexpectedToken, "1:5", "expected next token to be IDENT, got = instead", []token.TokenType{token.IDENT}},
+		{"(1 + 2;", CodeUnexpectedToken, "1:7", "expected next token to be ), got ; instead", []token.TokenType{token.RPAREN}},
+		{"add(1 2)", CodeUnexpectedToken, "1:7", "expected next token to be , or ), got INT instead", []token.TokenType{token.COMMA, token.RPAREN}},
+		{"[1, 2", CodeUnexpectedToken, "1:6", "expected next token to be , or ], got EOF instead", []token.TokenType{token.COMMA, token.RBRACKET}},
+		{"a[1 2]", CodeUnexpectedToken, "1:5", "expected next token to be ] or :, got INT instead", []token.TokenType{token.RBRACKET, token.COLON}},
+		{"a[1:2 3]", CodeUnexpectedToken, "1:7", "expected next token to be : or ], got INT instead", []token.TokenType{token.COLON, token.RBRACKET}},
+		{"a[::1:]", CodeUnexpectedToken, "1:6", "expected next token to be ], got : instead", []token.TokenType{token.RBRACKET}},
+		{"let [a, ...b, c] = xs;", CodeUnexpectedToken, "1:13", "expected next token to be ], got , instead", []token.TokenType{token.RBRACKET}},
+		{"let {a b} = h;", CodeUnexpectedToken, "1:8", "expected next token to be , or }, got IDENT instead", []token.TokenType{token.COMMA, token.RBRACE}},
+		{"let [1] = xs;", CodeUnexpectedToken, "1:6", "expected pattern, got INT instead", []token.TokenType{token.IDENT, token.LBRACKET, token.LBRACE}},
+		{"fn(a b) {}", CodeUnexpectedToken, "1:6", "expected next token to be , or ), got IDENT instead", []token.TokenType{token.COMMA, token.RPAREN}},
+		{"fn(a = 1, b) {}", CodeUnexpectedToken, "1:11", "non-default parameter b follows default parameter", []token.TokenType{token.ASSIGN}},
+		{"fn(...a, b) {}", CodeUnexpectedToken, "1:8", "expected next token to be ), got , instead", []token.TokenType{token.RPAREN}},
+		{"fn(a = b = 1) {}", CodeUnexpectedToken, "1:10", "expected next token to be , or ), got = instead", []token.TokenType{token.COMMA, token.RPAREN}},
+		{"f(a: 1, 2)", CodeUnexpectedToken, "1:9", "positional argument follows keyword argument", nil},
+		{"f(a: 1, ...xs)", CodeUnexpectedToken, "1:9", "positional argument follows keyword argument", nil},
+		{"[a: 1]", CodeUnexpectedToken, "1:3", "expected next token to be , or ], got : instead", []token.TokenType{token.COMMA, token.RBRACKET}},
+		{"match (x) { 1 => a b }", CodeUnexpectedToken, "1:20", "expected next token to be , or }, got IDENT instead", []token.TokenType{token.COMMA, token.RBRACE}},
+		{"match (x) { 1 = a }", CodeUnexpectedToken, "1:15", "expected next token to be =>, got = instead", []token.TokenType{token.ARROW}},
+		{"match x { _ => 1 }", CodeUnexpectedToken, "1:7", "expected next token to be (, got IDENT instead", []token.TokenType{token.LPAREN}},
+		{"match (x) { (a) => 1 }", CodeUnexpectedToken, "1:13", "expected pattern, got ( instead", []token.TokenType{token.IDENT, token.LBRACKET, token.LBRACE}},
+		{"try { a }", CodeUnexpectedToken, "1:10", "expected next token to be CATCH or FINALLY, got EOF instead", []token.TokenType{token.CATCH, token.FINALLY}},
+		{"try { a } catch (1) { b }", CodeUnexpectedToken, "1:18", "expected next token to be IDENT, got INT instead", []token.TokenType{token.IDENT}},
+		{"try a", CodeUnexpectedToken, "1:5", "expected next token to be {, got IDENT instead", []token.TokenType{token.LBRACE}},
+		{"if (true) { puts(1)", CodeUnexpectedToken, "1:20", "expected } to close block, got EOF instead", []token.TokenType{token.RBRACE}},
+		{"fn f() { 1", CodeUnexpectedToken, "1:11", "expected } to close block, got EOF instead", []token.TokenType{token.RBRACE}},
+		{"1 + ;", CodeMissingExpression, "1:5", "no prefix parse function for ; found", nil},
+		{"99999999999999999999", CodeNumberOverflow, "1:1", "integer literal 99999999999999999999 overflows int64", nil},
+		{"0x8000_0000_0000_0000", CodeNumberOverflow, "1:1", "integer literal 0x8000_0000_0000_0000 overflows int64", nil},
+		{"1e400", CodeNumberOverflow, "1:1", "float literal 1e400 is out of range", nil},
+		{"0b102", CodeIllegalToken, "1:1", `invalid digit '2' in binary literal`, nil},
+		{"1 @ 2", CodeIllegalToken, "1:3", `illegal character "@"`, nil},
+		{"let @ = 2", CodeIllegalToken, "1:5", `illegal character "@"`, nil},
+		{"1 + /* 2", CodeIllegalToken, "1:5", "block comment not terminated", nil},
+		{`let s = "abc`, CodeIllegalToken, "1:9", "string literal not terminated", nil},
+		{"f(x++3)", CodeUnexpectedToken, "1:6", "expected next token to be , or ), got INT instead", []token.TokenType{token.COMMA, token.RPAREN}},
+		{"f(x--3)", CodeUnexpectedToken, "1:6", "expected next token to be , or ), got INT instead", []token.TokenType{token.COMMA, token.RPAREN}},
+		{`"a ${x y}"`, CodeUnexpectedToken, "1:8", "expected } to close string interpolation, got IDENT instead", []token.TokenType{token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL}},
+		{`"a ${x"`, CodeIllegalToken, "1:7", "string literal not terminated", nil},
+		{`"a ${x`, CodeUnexpectedToken, "1:7", "expected } to close string interpolation, got EOF instead", []token.TokenType{token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != 1 {
+			t.Errorf("input %q: expected 1 diagnostic. got=%d (%v)", tt.input, len(diagnostics), p.Errors())
+			continue
+		}
+		d := diagnostics[0]
+		if d.Severity != SeverityError {
+			t.Errorf("input %q: severity wrong. got=%s", tt.input, d.Severity)
+		}
+		if d.Code != tt.expectedCode {
+			t.Errorf("input %q: code wrong. expected=%s, got=%s", tt.input, tt.expectedCode, d.Code)
+		}
+		if d.Pos.String() != tt.expectedPos {
+			t.Errorf("input %q: position wrong. expected=%s, got=%s", tt.input, tt.expectedPos, d.Pos)
+		}
+		if d.Message != tt.expectedMessage {
+			t.Errorf("input %q: message wrong. expected=%q, got=%q", tt.input, tt.expectedMessage, d.Message)
+		}
+		if fmt.Sprint(d.Expected) != fmt.Sprint(tt.expectedExpected) {
+			t.Errorf("input %q: expected tokens wrong. expected=%v, got=%v", tt.input, tt.expectedExpected, d.Expected)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	input := `let x 5 + 6 * 7;
+let y = 10;
+fn foo(a) {
+	let = 1;
+	a + ;
+	return a;
+}
+let z = (1 + 2;
+y;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	expected := []string{
+		"1:7: error[E0001]: expected next token to be =, got INT instead",
+		"4:6: error[E0001]: expected next token to be IDENT, got = instead",
+		"5:6: error[E0002]: no prefix parse function for ; found",
+		"8:15: error[E0001]: expected next token to be ), got ; instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%q)", len(expected), len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i])
+		}
+	}
+	// 出错的语句被丢弃 其余语句正常解析
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	testLetStatement(t, program.Statements[0], "y")
+	fn, ok := program.Statements[1].(*ast.FunctionDeclarationStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.FunctionDeclarationStatement. got=%T", program.Statements[1])
+	}
+	if len(fn.Body.Statements) != 1 {
+		t.Fatalf("fn.Body.Statements does not contain 1 statements. got=%d", len(fn.Body.Statements))
+	}
+	if program.Statements[2].String() != "y" {
+		t.Errorf("program.Statements[2] wrong. got=%q", program.Statements[2].String())
+	}
+}
+
+func TestParsingInterpolatedString(t *testing.T) {
+	input := `"hello ${name}, you are ${age + 1}"`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	is, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if len(is.Parts) != 4 {
+		t.Fatalf("len(is.Parts) not 4. got=%d", len(is.Parts))
+	}
+	if sl, ok := is.Parts[0].(*ast.StringLiteral); !ok || sl.Value != "hello " {
+		t.Errorf("is.Parts[0] wrong. got=%T (%s)", is.Parts[0], is.Parts[0])
+	}
+	testIdentifier(t, is.Parts[1], "name")
+	if sl, ok := is.Parts[2].(*ast.StringLiteral); !ok || sl.Value != ", you are " {
+		t.Errorf("is.Parts[2] wrong. got=%T (%s)", is.Parts[2], is.Parts[2])
+	}
+	testInfixExpression(t, is.Parts[3], "age", "+", 1)
+	if is.String() != `"hello ${name}, you are ${(age + 1)}"` {
+		t.Errorf("is.String() wrong. got=%q", is.String())
+	}
+}
@@ -0,0 +1,46 @@
+package parser
+
+import "monkey/ast"
+
+// markTailCalls 标记函数体中处于尾部位置的函数调用
+// 尾部位置包括 return 语句的返回值以及函数体的最后一个表达式
+// 求值器对尾调用使用蹦床(trampoline)执行 复用当前的 Go 栈帧
+func markTailCalls(body *ast.BlockStatement) {
+	markTailBlock(body, true)
+}
+
+// markTailBlock 标记语句块中的尾调用 tail 表示语句块的值是否直接作为函数的返回值
+// 嵌套的函数字面量在解析时已经单独标记 这里不再进入
+func markTailBlock(block *ast.BlockStatement, tail bool) {
+	if block == nil {
+		return
+	}
+	for i, stmt := range block.Statements {
+		switch s := stmt.(type) {
+		case *ast.ReturnStatement:
+			markTailExpression(s.ReturnValue, true)
+		case *ast.ExpressionStatement:
+			markTailExpression(s.Expression, tail && i == len(block.Statements)-1)
+		case *ast.WhileStatement:
+			markTailBlock(s.Body, false)
+		case *ast.ForStatement:
+			markTailBlock(s.Body, false)
+		}
+		// try 语句中调用产生的错误需要交给 catch 和 finally 处理 因此其中的调用都不是尾调用
+	}
+}
+
+// markTailExpression 标记表达式中的尾调用 tail 表示表达式的值是否直接作为函数的返回值
+func markTailExpression(exp ast.Expression, tail bool) {
+	switch e := exp.(type) {
+	case *ast.CallExpression:
+		e.Tail = tail
+	case *ast.IfExpression:
+		markTailBlock(e.Consequence, tail)
+		markTailBlock(e.Alternative, tail)
+	case *ast.MatchExpression:
+		for _, arm := range e.Arms {
+			markTailBlock(arm.Body, tail)
+		}
+	}
+}
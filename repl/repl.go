@@ -12,9 +12,9 @@ import (
 
 const Prompt = ">> "
 
-func Start(in io.Reader, out io.Writer) {
+// Start 逐行读取并求值 所有输入共享同一个作用域 env
+func Start(in io.Reader, out io.Writer, env *object.Environment) {
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
 	for {
 		fmt.Fprint(out, Prompt)
 		scanned := scanner.Scan()
@@ -25,11 +25,16 @@ func Start(in io.Reader, out io.Writer) {
 		lex := lexer.New(line)
 		p := parser.New(lex)
 		prog := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if len(p.Diagnostics()) != 0 {
+			printParserErrors(out, line, p.Diagnostics())
 			continue
 		}
 		evaluated := evaluator.Eval(prog, env)
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, err.Traceback())
+			io.WriteString(out, "\n")
+			continue
+		}
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -37,8 +42,9 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// printParserErrors 输出带有源码行和 ^ 标记的诊断信息
+func printParserErrors(out io.Writer, source string, diagnostics []*parser.Diagnostic) {
+	for _, d := range diagnostics {
+		io.WriteString(out, d.Render(source))
 	}
 }
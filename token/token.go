@@ -1,6 +1,9 @@
 package token
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 type TokenType byte
 
@@ -11,6 +14,7 @@ func (t TokenType) String() string {
 var tokenTypeStringMap = map[TokenType]string{
 	ILLEGAL:   "ILLEGAL",
 	EOF:       "EOF",
+	COMMENT:   "COMMENT",
 	IDENT:     "IDENT",
 	INT:       "INT",
 	FLOAT:     "FLOAT",
@@ -44,22 +48,91 @@ var tokenTypeStringMap = map[TokenType]string{
 	IF:        "IF",
 	ELSE:      "ELSE",
 	RETURN:    "RETURN",
+
+	TEMPLATE_HEAD:   "TEMPLATE_HEAD",
+	TEMPLATE_MIDDLE: "TEMPLATE_MIDDLE",
+	TEMPLATE_TAIL:   "TEMPLATE_TAIL",
+	AND:             "&&",
+	OR:              "||",
+	PERCENT:         "%",
+	POWER:           "**",
+	DIV:             "DIV",
+	AMPERSAND:       "&",
+	PIPE:            "|",
+	CARET:           "^",
+	TILDE:           "~",
+	SHL:             "<<",
+	SHR:             ">>",
+	WHILE:           "WHILE",
+	BREAK:           "BREAK",
+	CONTINUE:        "CONTINUE",
+	FOR:             "FOR",
+	IN:              "IN",
+	DOTDOT:          "..",
+	PLUS_ASSIGN:     "+=",
+	MINUS_ASSIGN:    "-=",
+	ASTERISK_ASSIGN: "*=",
+	SLASH_ASSIGN:    "/=",
+	PERCENT_ASSIGN:  "%=",
+	INCREMENT:       "++",
+	DECREMENT:       "--",
+	ELLIPSIS:        "...",
+	ARROW:           "=>",
+	MATCH:           "MATCH",
+	TRY:             "TRY",
+	CATCH:           "CATCH",
+	FINALLY:         "FINALLY",
+	THROW:           "THROW",
+}
+
+// Position 表示源码中的一个位置
+type Position struct {
+	Filename string // 文件名，可以为空
+	Offset   int    // 字节偏移量，从 0 开始
+	Line     int    // 行号，从 1 开始
+	Column   int    // 列号，从 1 开始
+}
+
+// IsValid 判断位置是否有效，零值 Position 是无效的
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 返回 file:line:column 形式的位置描述，没有文件名时返回 line:column
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
 }
 
 type Token struct {
 	Type    TokenType // 词元类型
 	Literal string    // 字面量
+	Pos     Position  // 词元第一个字符的位置
+	End     Position  // 词元最后一个字符之后的位置
 }
 
 const (
 	ILLEGAL TokenType = iota // 未知的词法单元或字符
 	EOF                      // 文件结尾
+	COMMENT                  // 注释，只有开启后词法分析器才会返回
 
 	// 标识符+字面量
 	IDENT // add, foobar, x, y, ...
 	INT   // 1343456
 	FLOAT
 	STRING
+	TEMPLATE_HEAD   // "a ${ 插值字符串的第一个片段
+	TEMPLATE_MIDDLE // } b ${ 插值字符串两个插值之间的片段
+	TEMPLATE_TAIL   // } c" 插值字符串的最后一个片段
 
 	// 运算符
 	ASSIGN
@@ -75,6 +148,28 @@ const (
 	EQ
 	NOT_EQ
 	DOT
+	AND       // &&
+	OR        // ||
+	PERCENT   // %
+	POWER     // **
+	AMPERSAND // &
+	PIPE      // |
+	CARET     // ^
+	TILDE     // ~
+	SHL       // <<
+	SHR       // >>
+	DOTDOT    // .. 左闭右开的区间
+	ELLIPSIS  // ... 剩余元素
+	ARROW     // => match 分支
+
+	// 复合赋值运算符
+	PLUS_ASSIGN     // +=
+	MINUS_ASSIGN    // -=
+	ASTERISK_ASSIGN // *=
+	SLASH_ASSIGN    // /=
+	PERCENT_ASSIGN  // %=
+	INCREMENT       // ++
+	DECREMENT       // --
 
 	// 分隔符
 	COMMA
@@ -95,16 +190,38 @@ const (
 	IF
 	ELSE
 	RETURN
+	DIV // div 整除运算符，// 已经被用作注释
+	WHILE
+	BREAK
+	CONTINUE
+	FOR
+	IN
+	MATCH
+	TRY
+	CATCH
+	FINALLY
+	THROW
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"div":      DIV,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
+	"match":    MATCH,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -114,8 +231,13 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
+// DetermineNumberType 判断数字字面量是整数还是浮点数
+// 带有 0x 0b 0o 前缀的字面量总是整数, 其余字面量含有小数点或者指数时为浮点数
 func DetermineNumberType(number string) TokenType {
-	if strings.Contains(number, ".") {
+	if len(number) > 1 && number[0] == '0' && strings.ContainsRune("xXbBoO", rune(number[1])) {
+		return INT
+	}
+	if strings.ContainsAny(number, ".eE") {
 		return FLOAT
 	}
 	return INT